@@ -0,0 +1,140 @@
+package pathtransfer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/tidwall/gjson"
+)
+
+var ERROR_TRANSFER_ARRAY_MODIFIER = errors.New("array modifier invalid")
+
+const (
+	ArrayModifier_Sort   = "sort"   // 按元素中的路径排序,- 开头降序,可以多个 sort=-score,sort=name
+	ArrayModifier_Unique = "unique" // 按元素中的路径去重,保留第一个
+	ArrayModifier_Limit  = "limit"  // 只保留前N个
+)
+
+// ArraySortKey 排序字段
+type ArraySortKey struct {
+	Path Path `json:"path"`
+	Desc bool `json:"desc"`
+}
+
+// ArrayModifier 来源路径中 # 的数组修改,如 services.#[sort=-name,unique=id,limit=10].servers.#
+// 执行顺序固定为 去重、排序、截取,和书写顺序无关
+type ArrayModifier struct {
+	Sort   []ArraySortKey `json:"sort,omitempty"`
+	Unique Path           `json:"unique,omitempty"`
+	Limit  int            `json:"limit,omitempty"` // 0 表示不限制
+}
+
+// isArraySegment 判断路径层级是否为数组标识 # 或者带修改的 #[...]
+func isArraySegment(seg string) bool {
+	return seg == "#" || strings.HasPrefix(seg, "#[") && strings.HasSuffix(seg, "]")
+}
+
+// hasArrayModifier 判断路径中是否有带修改的 #[...]
+func hasArrayModifier(path Path) bool {
+	for _, seg := range splitPath(path) {
+		if seg != "#" && isArraySegment(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseArrayModifier 解析 #[sort=-name,unique=id,limit=10],# 返回空修改
+func ParseArrayModifier(seg string) (modifier ArrayModifier, err error) {
+	if !isArraySegment(seg) {
+		err = errors.WithMessagef(ERROR_TRANSFER_ARRAY_MODIFIER, "array segment required,got:%s", seg)
+		return modifier, err
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(seg, "#["), "]")
+	if seg == "#" || strings.TrimSpace(body) == "" {
+		return modifier, nil
+	}
+	for _, option := range strings.Split(body, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok || value == "" {
+			err = errors.WithMessagef(ERROR_TRANSFER_ARRAY_MODIFIER, "option format required name=value,got:%s", option)
+			return modifier, err
+		}
+		switch name {
+		case ArrayModifier_Sort:
+			sortKey := ArraySortKey{Path: Path(strings.TrimPrefix(value, "-")), Desc: strings.HasPrefix(value, "-")}
+			modifier.Sort = append(modifier.Sort, sortKey)
+		case ArrayModifier_Unique:
+			modifier.Unique = Path(value)
+		case ArrayModifier_Limit:
+			modifier.Limit, err = cast.ToIntE(value)
+			if err != nil || modifier.Limit < 0 {
+				err = errors.WithMessagef(ERROR_TRANSFER_ARRAY_MODIFIER, "limit require positive int,got:%s", value)
+				return modifier, err
+			}
+		default:
+			err = errors.WithMessagef(ERROR_TRANSFER_ARRAY_MODIFIER, "unknown option:%s", name)
+			return modifier, err
+		}
+	}
+	return modifier, nil
+}
+
+// Modify 修改数组元素
+func (modifier ArrayModifier) Modify(items []gjson.Result) (newItems []gjson.Result) {
+	newItems = make([]gjson.Result, 0, len(items))
+	seen := map[string]struct{}{}
+	for _, item := range items {
+		if modifier.Unique != "" {
+			key := item.Get(modifier.Unique.String()).Raw
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		newItems = append(newItems, item)
+	}
+	if len(modifier.Sort) > 0 {
+		sort.SliceStable(newItems, func(i, j int) bool {
+			for _, sortKey := range modifier.Sort {
+				c := compareResult(newItems[i].Get(sortKey.Path.String()), newItems[j].Get(sortKey.Path.String()))
+				if c == 0 {
+					continue
+				}
+				if sortKey.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	if modifier.Limit > 0 && len(newItems) > modifier.Limit {
+		newItems = newItems[:modifier.Limit]
+	}
+	return newItems
+}
+
+// compareResult 比较两个值,都是数字时按数值比较,否则按字符串比较,不存在的值最小
+func compareResult(a gjson.Result, b gjson.Result) (c int) {
+	switch {
+	case !a.Exists() && !b.Exists():
+		return 0
+	case !a.Exists():
+		return -1
+	case !b.Exists():
+		return 1
+	}
+	if a.Type == gjson.Number && b.Type == gjson.Number {
+		switch {
+		case a.Num < b.Num:
+			return -1
+		case a.Num > b.Num:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a.String(), b.String())
+}
@@ -0,0 +1,40 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyArrayModifier(t *testing.T) {
+	data := `{"services":[
+		{"name":"b","servers":[{"url":"b2","weight":2},{"url":"b1","weight":10},{"url":"b3","weight":2}]},
+		{"name":"a","servers":[{"url":"a1","weight":1}]},
+		{"name":"b","servers":[]}
+	]}`
+	transfers := pathtransfer.Parse(`
+services.#[unique=name,sort=name].name:Api.services.#.name
+services.#[unique=name,sort=name].servers.#[sort=-weight,sort=url,limit=2].url:Api.services.#.servers.#.url
+services.#[sort=-name,limit=1].name:Api.first
+	`)
+	out, err := transfers.Apply([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{"services":[{"name":"a","servers":[{"url":"a1"}]},{"name":"b","servers":[{"url":"b1"},{"url":"b2"}]}],"first":["b"]}}`
+	require.JSONEq(t, expected, string(out))
+
+	gjsonOut := gjson.Get(data, transfers[2:].GjsonPath()).String()
+	require.JSONEq(t, `{"Api":{"first":["b"]}}`, gjsonOut)
+
+	modifier, err := pathtransfer.ParseArrayModifier("#[sort=-weight,unique=id,limit=10]")
+	require.NoError(t, err)
+	require.Equal(t, pathtransfer.ArrayModifier{
+		Sort:   []pathtransfer.ArraySortKey{{Path: "weight", Desc: true}},
+		Unique: "id",
+		Limit:  10,
+	}, modifier)
+
+	_, err = pathtransfer.Parse(`services.#[top=1].name:Api.names`).Apply([]byte(data))
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_ARRAY_MODIFIER)
+}
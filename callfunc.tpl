@@ -1,41 +1,213 @@
 {{- define "go" -}}
-{{$package:=.FirstPackage}}
-{{if $package}}
-package {{.FirstPackage}}
-{{end}}
+// 生成的调用脚本,入参、出参均为json字符串
+package {{.GoPackage}}
+
 import (
-    "github.com/spf13/cast"
-    "github.com/suifengpiao14/goscript/yaegi"
-	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
+{{- range $path:=.GoImports}}
+	"{{$path}}"
+{{- end}}
 )
 {{range $callFunc:=.}}
-func Call{{$callFunc.FuncName}}(input string) (outputDTO *yaegi.OutputDTO){
-        {{range $arg:=$callFunc.Input -}}
-            {{$arg.Name}}Str:=gjson.Get(input, "{{$arg.Path.TrimIONamespace}}").String()
-            {{if $arg.TypeConvertFunc}}
-                {{$arg.Name}} := cast.{{$arg.TypeConvertFunc}}({{$arg.Name}}Str)
-            {{else}}
-                {{$arg.Name}} := {{$arg.Name}}Str
-            {{end}}
-           
-        {{ end}}
-        var out string
+func Call{{$callFunc.FuncName}}(input string) (outputDTO *yaegi.OutputDTO) {
+	outputDTO = &yaegi.OutputDTO{}
+	{{- range $arg:=$callFunc.Input.Uniq}}
+	{{- if $arg.IsComplex}}
+	var {{$arg.GoVarName}} {{$arg.GoType}}
+	if raw := gjson.Get(input, "{{$arg.Name}}").Raw; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &{{$arg.GoVarName}}); err != nil {
+			outputDTO.Err = err
+			return outputDTO
+		}
+	}
+	{{- else}}
+	{{$arg.GoVarName}} := {{$arg.GoValue "input"}}
+	{{- end}}
+	{{- end}}
+	{{- if $callFunc.Output}}
+	{ // 避免局部变量冲突
+		{{$callFunc.Output.Uniq.GoVarNames}} := {{$callFunc.FuncName}}({{$callFunc.Input.Uniq.GoVarNames}})
+		var out string
 		var err error
-		outputDTO = &yaegi.OutputDTO{}
-        {// 避免局部变量冲突
-            {{$callFunc.Output.Names}}:={{$callFunc.FuncName}}({{$callFunc.Input.Names}})
-            {{range $arg:=$callFunc.Output -}}
-                out, err = sjson.Set(out, "{{$arg.Path.TrimIONamespace}}", {{$arg.Name}})
-                if err != nil {
-                   outputDTO.Err = err
-				    return outputDTO
-                }
-            {{ end}}
-        }
-        outputDTO.Data = out
-		return outputDTO
+		{{- range $arg:=$callFunc.Output.Uniq}}
+		out, err = {{$arg.GoSetter "out"}}
+		if err != nil {
+			outputDTO.Err = err
+			return outputDTO
+		}
+		{{- end}}
+		outputDTO.Data = out
 	}
+	{{- else}}
+	{{$callFunc.FuncName}}({{$callFunc.Input.Uniq.GoVarNames}})
+	{{- end}}
+	return outputDTO
+}
+{{end}}
+{{- end -}}
+
+{{- define "javascript" -}}
+// 生成的调用脚本(兼容 goja),入参、出参均为json字符串,多个出参的函数返回数组
+function __get(obj, path) {
+    var keys = path.split(".");
+    for (var i = 0; i < keys.length; i++) {
+        if (obj === null || typeof obj !== "object") {
+            return undefined;
+        }
+        obj = obj[keys[i]];
+    }
+    return obj;
+}
+
+function __set(obj, path, value) {
+    var keys = path.split(".");
+    for (var i = 0; i < keys.length - 1; i++) {
+        if (obj[keys[i]] === null || typeof obj[keys[i]] !== "object") {
+            obj[keys[i]] = {};
+        }
+        obj = obj[keys[i]];
+    }
+    obj[keys[keys.length - 1]] = value;
+}
+{{range $callFunc:=.}}
+function Call{{$callFunc.FuncName}}(input) {
+    var __input = JSON.parse(input || "{}");
+    {{- range $arg:=$callFunc.Input}}
+    var {{$arg.ScriptVarName "javascript"}} = __get(__input, "{{$arg.Path.TrimIONamespace}}");
+    {{- if eq $arg.ScriptType "int"}}
+    {{$arg.ScriptVarName "javascript"}} = {{$arg.ScriptVarName "javascript"}} === undefined || {{$arg.ScriptVarName "javascript"}} === null ? 0 : parseInt({{$arg.ScriptVarName "javascript"}}, 10);
+    {{- else if eq $arg.ScriptType "number"}}
+    {{$arg.ScriptVarName "javascript"}} = {{$arg.ScriptVarName "javascript"}} === undefined || {{$arg.ScriptVarName "javascript"}} === null ? 0 : Number({{$arg.ScriptVarName "javascript"}});
+    {{- else if eq $arg.ScriptType "string"}}
+    {{$arg.ScriptVarName "javascript"}} = {{$arg.ScriptVarName "javascript"}} === undefined || {{$arg.ScriptVarName "javascript"}} === null ? "" : String({{$arg.ScriptVarName "javascript"}});
+    {{- else if eq $arg.ScriptType "bool"}}
+    {{$arg.ScriptVarName "javascript"}} = {{$arg.ScriptVarName "javascript"}} === true || {{$arg.ScriptVarName "javascript"}} === "true" || {{$arg.ScriptVarName "javascript"}} === 1 || {{$arg.ScriptVarName "javascript"}} === "1";
+    {{- end}}
+    {{- end}}
+    var __result = {{$callFunc.FuncName}}({{$callFunc.Input.ScriptVarNames "javascript"}});
+    var __out = {};
+    {{- if eq (len $callFunc.Output) 1}}
+    {{- range $arg:=$callFunc.Output}}
+    __set(__out, "{{$arg.Path.TrimIONamespace}}", __result);
+    {{- end}}
+    {{- else}}
+    {{- range $i, $arg:=$callFunc.Output}}
+    __set(__out, "{{$arg.Path.TrimIONamespace}}", __result[{{$i}}]);
+    {{- end}}
+    {{- end}}
+    return JSON.stringify(__out);
+}
+{{end}}
+{{- end -}}
+
+{{- define "lua" -}}
+-- 生成的调用脚本,入参、出参均为json字符串,json 模块需提供 decode/encode(如 gopher-json、cjson)
+local json = require("json")
+
+local function __get(obj, path)
+    for key in string.gmatch(path, "[^.]+") do
+        if type(obj) ~= "table" then
+            return nil
+        end
+        obj = obj[key]
+    end
+    return obj
+end
+
+local function __set(obj, path, value)
+    local keys = {}
+    for key in string.gmatch(path, "[^.]+") do
+        table.insert(keys, key)
+    end
+    for i = 1, #keys - 1 do
+        if type(obj[keys[i]]) ~= "table" then
+            obj[keys[i]] = {}
+        end
+        obj = obj[keys[i]]
+    end
+    obj[keys[#keys]] = value
+end
+{{range $callFunc:=.}}
+function Call{{$callFunc.FuncName}}(input)
+    local __input = json.decode(input ~= "" and input or "{}")
+    {{- range $arg:=$callFunc.Input}}
+    local {{$arg.ScriptVarName "lua"}} = __get(__input, "{{$arg.Path.TrimIONamespace}}")
+    {{- if eq $arg.ScriptType "int"}}
+    {{$arg.ScriptVarName "lua"}} = math.floor(tonumber({{$arg.ScriptVarName "lua"}}) or 0)
+    {{- else if eq $arg.ScriptType "number"}}
+    {{$arg.ScriptVarName "lua"}} = tonumber({{$arg.ScriptVarName "lua"}}) or 0
+    {{- else if eq $arg.ScriptType "string"}}
+    {{$arg.ScriptVarName "lua"}} = {{$arg.ScriptVarName "lua"}} == nil and "" or tostring({{$arg.ScriptVarName "lua"}})
+    {{- else if eq $arg.ScriptType "bool"}}
+    {{$arg.ScriptVarName "lua"}} = {{$arg.ScriptVarName "lua"}} == true or {{$arg.ScriptVarName "lua"}} == "true" or {{$arg.ScriptVarName "lua"}} == 1 or {{$arg.ScriptVarName "lua"}} == "1"
+    {{- end}}
+    {{- end}}
+    {{if $callFunc.Output}}local {{$callFunc.Output.ScriptVarNames "lua"}} = {{end}}{{$callFunc.FuncName}}({{$callFunc.Input.ScriptVarNames "lua"}})
+    local __out = {}
+    {{- range $arg:=$callFunc.Output}}
+    __set(__out, "{{$arg.Path.TrimIONamespace}}", {{$arg.ScriptVarName "lua"}})
+    {{- end}}
+    return json.encode(__out)
+end
 {{end}}
 {{- end -}}
 
+{{- define "tengo" -}}
+// 生成的调用脚本,入参、出参均为json字符串,多个出参的函数返回数组;tengo 需先定义再引用,脚本需拼接在函数定义之后
+__json := import("json")
+__text := import("text")
+
+__get := func(obj, path) {
+    for key in __text.split(path, ".") {
+        if !is_map(obj) {
+            return undefined
+        }
+        obj = obj[key]
+    }
+    return obj
+}
+
+__set := func(obj, path, value) {
+    keys := __text.split(path, ".")
+    for i := 0; i < len(keys)-1; i++ {
+        if !is_map(obj[keys[i]]) {
+            obj[keys[i]] = {}
+        }
+        obj = obj[keys[i]]
+    }
+    obj[keys[len(keys)-1]] = value
+}
+{{range $callFunc:=.}}
+Call{{$callFunc.FuncName}} := func(input) {
+    __input := __json.decode(input == "" ? "{}" : input)
+    {{- range $arg:=$callFunc.Input}}
+    {{- if eq $arg.ScriptType "int"}}
+    {{$arg.ScriptVarName "tengo"}} := int(__get(__input, "{{$arg.Path.TrimIONamespace}}"), 0)
+    {{- else if eq $arg.ScriptType "number"}}
+    {{$arg.ScriptVarName "tengo"}} := float(__get(__input, "{{$arg.Path.TrimIONamespace}}"), 0.0)
+    {{- else if eq $arg.ScriptType "string"}}
+    {{$arg.ScriptVarName "tengo"}} := string(__get(__input, "{{$arg.Path.TrimIONamespace}}"), "")
+    {{- else if eq $arg.ScriptType "bool"}}
+    {{$arg.ScriptVarName "tengo"}} := __get(__input, "{{$arg.Path.TrimIONamespace}}")
+    {{$arg.ScriptVarName "tengo"}} = {{$arg.ScriptVarName "tengo"}} == true || {{$arg.ScriptVarName "tengo"}} == "true" || {{$arg.ScriptVarName "tengo"}} == 1 || {{$arg.ScriptVarName "tengo"}} == "1"
+    {{- else}}
+    {{$arg.ScriptVarName "tengo"}} := __get(__input, "{{$arg.Path.TrimIONamespace}}")
+    {{- end}}
+    {{- end}}
+    __result := {{$callFunc.FuncName}}({{$callFunc.Input.ScriptVarNames "tengo"}})
+    if is_error(__result) {
+        return __result
+    }
+    __out := {}
+    {{- if eq (len $callFunc.Output) 1}}
+    {{- range $arg:=$callFunc.Output}}
+    __set(__out, "{{$arg.Path.TrimIONamespace}}", __result)
+    {{- end}}
+    {{- else}}
+    {{- range $i, $arg:=$callFunc.Output}}
+    __set(__out, "{{$arg.Path.TrimIONamespace}}", __result[{{$i}}])
+    {{- end}}
+    {{- end}}
+    return string(__json.encode(__out))
+}
+{{end}}
+{{- end -}}
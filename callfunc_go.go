@@ -0,0 +1,210 @@
+package pathtransfer
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/suifengpiao14/funcs"
+)
+
+var (
+	ERROR_CALL_FUNC_MULTI_PACKAGE = errors.New("go call script require single package")
+)
+
+const (
+	CallFunc_Go_Default_Package = "main" // 函数没有包名时生成脚本的包名
+)
+
+// callFuncFormatters 生成脚本后的格式化,go 脚本使用 gofmt 格式化,同时可以校验语法
+var callFuncFormatters = map[string]func(src []byte) (formatted []byte, err error){
+	"go": format.Source,
+}
+
+// GroupByPackage 按包名分组,go 脚本一个文件只能属于一个包,多个包时分别生成
+func (cfs CallFuncs) GroupByPackage() (grouped map[string]CallFuncs) {
+	grouped = make(map[string]CallFuncs)
+	for _, cf := range cfs {
+		grouped[cf.Package] = append(grouped[cf.Package], cf)
+	}
+	return grouped
+}
+
+// Packages 包名,按名称排序
+func (cfs CallFuncs) Packages() (packages []string) {
+	packages = make([]string, 0)
+	for packageName := range cfs.GroupByPackage() {
+		packages = append(packages, packageName)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// Scripts 按包分别生成脚本,key 为包名
+func (cfs CallFuncs) Scripts(language string) (scripts map[string]string, err error) {
+	scripts = make(map[string]string)
+	for packageName, packageCallFuncs := range cfs.GroupByPackage() {
+		scripts[packageName], err = packageCallFuncs.Script(language)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scripts, nil
+}
+
+var goIdentifierReg = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// goIdentifier 转换为合法的go标识符
+func goIdentifier(name string) (identifier string) {
+	identifier = goIdentifierReg.ReplaceAllString(name, "_")
+	if identifier == "" || (identifier[0] >= '0' && identifier[0] <= '9') {
+		identifier = "_" + identifier
+	}
+	return identifier
+}
+
+// GoPackage go 脚本的包名,取包路径最后一段
+func (cfs CallFuncs) GoPackage() (packageName string) {
+	packageName = cfs.FirstPackage()
+	if index := strings.LastIndexAny(packageName, "./"); index > -1 {
+		packageName = packageName[index+1:]
+	}
+	if packageName == "" {
+		return CallFunc_Go_Default_Package
+	}
+	return goIdentifier(packageName)
+}
+
+// GoImports go 脚本需要导入的包,只导入用到的包
+func (cfs CallFuncs) GoImports() (imports []string) {
+	var useJson, useCast, useGjson, useSjson bool
+	for _, cf := range cfs {
+		for _, fp := range cf.Input {
+			useGjson = true
+			useJson = useJson || fp.IsComplex()
+			useCast = useCast || (!fp.IsComplex() && fp.TypeConvertFunc() != "")
+		}
+		useSjson = useSjson || len(cf.Output) > 0
+	}
+	if useJson {
+		imports = append(imports, "encoding/json")
+	}
+	if useCast {
+		imports = append(imports, "github.com/spf13/cast")
+	}
+	imports = append(imports, "github.com/suifengpiao14/goscript/yaegi")
+	if useGjson {
+		imports = append(imports, "github.com/tidwall/gjson")
+	}
+	if useSjson {
+		imports = append(imports, "github.com/tidwall/sjson")
+	}
+	return imports
+}
+
+// goReservedNames go 脚本中已使用的变量、包名,参数变量名需避开
+var goReservedNames = map[string]bool{
+	"input": true, "outputDTO": true, "out": true, "err": true, "raw": true,
+	"json": true, "cast": true, "yaegi": true, "gjson": true, "sjson": true,
+}
+
+// IsComplex 对象、数组类型参数,go 脚本中使用 json.Unmarshal 解析
+func (fp FuncParameter) IsComplex() bool {
+	return strings.EqualFold(fp.Type, "object") || strings.EqualFold(fp.Type, "array")
+}
+
+// GoType 参数在go 脚本中的类型,对象为匿名结构体(可以直接传给字段、json tag 相同的命名结构体),数组为切片
+func (fp FuncParameter) GoType() (goType string) {
+	switch strings.ToLower(fp.Type) {
+	case "int":
+		return "int"
+	case "int64":
+		return "int64"
+	case "number", "float", "float64":
+		return "float64"
+	case "bool", "boolean":
+		return "bool"
+	case "object":
+		if len(fp.Fields) == 0 {
+			return "map[string]interface{}"
+		}
+		return fp.Fields.goStruct()
+	case "array":
+		if len(fp.Fields) > 0 {
+			return fmt.Sprintf("[]%s", fp.Fields.goStruct())
+		}
+		if fp.ItemType != "" {
+			return fmt.Sprintf("[]%s", FuncParameter{Type: fp.ItemType}.GoType())
+		}
+		return "[]interface{}"
+	}
+	return "string"
+}
+
+// goStruct 字段定义生成匿名结构体,字段名为大驼峰,json tag 为参数名;数组元素字段(名称为空)时为元素类型
+func (fps FuncParameters) goStruct() (goType string) {
+	if len(fps) == 1 && fps[0].Name == "" {
+		return fps[0].GoType()
+	}
+	var w strings.Builder
+	w.WriteString("struct {\n")
+	for _, field := range fps {
+		fieldName := goIdentifier(funcs.CamelCase(delimitedKey(field.Name), true, false))
+		w.WriteString(fmt.Sprintf("%s %s `json:\"%s\"`\n", fieldName, field.GoType(), field.Name))
+	}
+	w.WriteString("}")
+	return w.String()
+}
+
+// GoVarName 参数在go 脚本中的变量名,避开关键字和脚本中已使用的名称
+func (fp FuncParameter) GoVarName() (name string) {
+	name = goIdentifier(fp.Name)
+	if token.IsKeyword(name) || goReservedNames[name] {
+		name = fmt.Sprintf("%sArg", name)
+	}
+	return name
+}
+
+// GoValue 从json字符串变量 inputVar 中获取基础类型参数值的go表达式,对象、数组参数使用 json.Unmarshal 解析
+func (fp FuncParameter) GoValue(inputVar string) (expr string) {
+	get := fmt.Sprintf("gjson.Get(%s, %q)", inputVar, fp.Name)
+	if fnName := fp.TypeConvertFunc(); fnName != "" {
+		return fmt.Sprintf("cast.%s(%s.String())", fnName, get)
+	}
+	return fmt.Sprintf("%s.String()", get)
+}
+
+// GoSetter 将参数值写入json字符串变量 outVar 的go表达式,对象、数组按json tag 写入完整结构
+func (fp FuncParameter) GoSetter(outVar string) (expr string) {
+	return fmt.Sprintf("sjson.Set(%s, %q, %s)", outVar, fp.Name, fp.GoVarName())
+}
+
+// Uniq 按参数名去重,对象参数的多个子路径合并为一个参数
+func (fps FuncParameters) Uniq() (uniq FuncParameters) {
+	uniq = make(FuncParameters, 0)
+	index := make(map[string]int)
+	for _, fp := range fps {
+		if i, ok := index[fp.Name]; ok {
+			if !uniq[i].IsComplex() {
+				uniq[i].Type = "object"
+			}
+			continue
+		}
+		index[fp.Name] = len(uniq)
+		uniq = append(uniq, fp)
+	}
+	return uniq
+}
+
+// GoVarNames go 脚本中的参数变量名,逗号分隔
+func (fps FuncParameters) GoVarNames() (names string) {
+	arr := make([]string, 0, len(fps))
+	for _, fp := range fps {
+		arr = append(arr, fp.GoVarName())
+	}
+	return strings.Join(arr, ", ")
+}
@@ -0,0 +1,160 @@
+package pathtransfer_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+// goScriptStubPackages 生成脚本依赖包的桩代码,只保留脚本用到的声明
+var goScriptStubPackages = map[string]string{
+	"encoding/json": `package json
+func Unmarshal(data []byte, v interface{}) error { return nil }
+`,
+	"github.com/spf13/cast": `package cast
+func ToInt(i interface{}) int { return 0 }
+func ToInt64(i interface{}) int64 { return 0 }
+func ToFloat64(i interface{}) float64 { return 0 }
+func ToBool(i interface{}) bool { return false }
+`,
+	"github.com/suifengpiao14/goscript/yaegi": `package yaegi
+type OutputDTO struct {
+	Data string
+	Err  error
+}
+`,
+	"github.com/tidwall/gjson": `package gjson
+type Result struct{ Raw string }
+func (r Result) String() string { return r.Raw }
+func Get(json, path string) Result { return Result{} }
+`,
+	"github.com/tidwall/sjson": `package sjson
+func Set(json, path string, value interface{}) (string, error) { return json, nil }
+func SetRaw(json, path, value string) (string, error) { return json, nil }
+`,
+}
+
+type goScriptStubImporter struct {
+	fset     *token.FileSet
+	packages map[string]*types.Package
+}
+
+func (im *goScriptStubImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := im.packages[path]; ok {
+		return pkg, nil
+	}
+	src, ok := goScriptStubPackages[path]
+	if !ok {
+		return nil, fmt.Errorf("stub package not found:%s", path)
+	}
+	file, err := parser.ParseFile(im.fset, path+".go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := (&types.Config{}).Check(path, im.fset, []*ast.File{file}, nil)
+	if err != nil {
+		return nil, err
+	}
+	im.packages[path] = pkg
+	return pkg, nil
+}
+
+// typeCheckGoScript 使用 go/types 检查生成的脚本,funcStub 为被调用函数的桩代码(和脚本同包)
+func typeCheckGoScript(t *testing.T, script string, funcStub string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	scriptFile, err := parser.ParseFile(fset, "script.go", script, 0)
+	require.NoError(t, err, script)
+	stubFile, err := parser.ParseFile(fset, "stub.go", funcStub, 0)
+	require.NoError(t, err)
+	conf := types.Config{Importer: &goScriptStubImporter{fset: fset, packages: map[string]*types.Package{}}}
+	_, err = conf.Check(scriptFile.Name.Name, fset, []*ast.File{scriptFile, stubFile}, nil)
+	require.NoError(t, err, script)
+}
+
+func TestGoCallScriptCompile(t *testing.T) {
+	t.Run("limit", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.vocabulary.SetLimit.input.index@int:Dictionary.pagination.index
+func.vocabulary.SetLimit.input.size@int:Dictionary.pagination.size
+func.vocabulary.SetLimit.output.offset@int:Dictionary.limit.offset
+func.vocabulary.SetLimit.output.size@int:Dictionary.limit.size
+	`)
+		script, err := transfers.GetCallFnScript("go")
+		require.NoError(t, err)
+		typeCheckGoScript(t, script, `package vocabulary
+func SetLimit(index int, size int) (int, int) { return index * size, size }
+`)
+	})
+	t.Run("types and names", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.Log.input.input:data.message
+func.Now.output.time:data.now
+func.Check.input.type@string:data.type
+func.Check.input.price@number:data.price
+func.Check.input.enabled@bool:data.enabled
+func.Check.input.id@int64:data.id
+func.Check.input.filter.name:data.filter.name
+func.Check.input.filter.age@int:data.filter.age
+func.Check.input.tags#@string:data.tags
+func.Check.input.items#.id@int:data.items.#.id
+func.Check.input.items#.name:data.items.#.name
+func.Check.output.func@bool:data.ok
+func.Check.output.result.total@int:data.total
+	`)
+		script, err := transfers.GetCallFnScript("go")
+		require.NoError(t, err)
+		require.Contains(t, script, "package main")
+		typeCheckGoScript(t, script, `package main
+func Log(message string) {}
+func Now() string { return "" }
+type Filter struct {
+	Name string `+"`json:\"name\"`"+`
+	Age  int    `+"`json:\"age\"`"+`
+}
+type Result struct {
+	Total int `+"`json:\"total\"`"+`
+}
+func Check(typ string, price float64, enabled bool, id int64, filter Filter, tags []string, items []struct {
+	Id   int    `+"`json:\"id\"`"+`
+	Name string `+"`json:\"name\"`"+`
+}) (bool, Result) {
+	return true, Result{}
+}
+`)
+		transfers = pathtransfer.Parse(`func.Log.input.input:data.message`)
+		script, err = transfers.GetCallFnScript("go")
+		require.NoError(t, err)
+		require.NotContains(t, script, "cast")
+		require.NotContains(t, script, "sjson")
+		typeCheckGoScript(t, script, `package main
+func Log(message string) {}
+`)
+	})
+	t.Run("multi package", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.user.TrimName.input.name:data.userName
+func.user.TrimName.output.name:data.userName
+func.vocabulary.SetLimit.input.index@int:Dictionary.pagination.index
+func.vocabulary.SetLimit.output.offset@int:Dictionary.limit.offset
+	`)
+		_, err := transfers.GetCallFnScript("go")
+		require.ErrorIs(t, err, pathtransfer.ERROR_CALL_FUNC_MULTI_PACKAGE)
+		callFuncs, err := transfers.CallFuncs()
+		require.NoError(t, err)
+		scripts, err := callFuncs.Scripts("go")
+		require.NoError(t, err)
+		typeCheckGoScript(t, scripts["user"], `package user
+func TrimName(name string) string { return name }
+`)
+		typeCheckGoScript(t, scripts["vocabulary"], `package vocabulary
+func SetLimit(index int) int { return index }
+`)
+	})
+}
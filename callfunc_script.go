@@ -0,0 +1,47 @@
+package pathtransfer
+
+import (
+	"strings"
+)
+
+// callFuncScriptReservedNames javascript、lua、tengo 脚本中的关键字、内置名称和调用脚本已使用的名称,参数变量名需避开
+var callFuncScriptReservedNames = map[string]map[string]bool{
+	"javascript": scriptNameSet(`break case catch class const continue debugger default delete do else enum export extends false finally for function
+		if implements import in instanceof interface let new null package private protected public return static super switch this throw true try typeof
+		var void while with yield await arguments eval undefined NaN Infinity JSON Number String Boolean parseInt input`),
+	"lua": scriptNameSet(`and break do else elseif end false for function goto if in local nil not or repeat return then true until while
+		json math string table type tonumber tostring input`),
+	"tengo": scriptNameSet(`break continue else for func error export false if immutable import in return true undefined
+		int float string bool len is_map is_error input`),
+}
+
+func scriptNameSet(names string) (set map[string]bool) {
+	set = make(map[string]bool)
+	for _, name := range strings.Fields(names) {
+		set[name] = true
+	}
+	return set
+}
+
+// ScriptVarName 参数在 javascript、lua、tengo 脚本中的变量名,转换为合法标识符并避开关键字、已使用的名称(__ 开头的名称为脚本内部使用)
+func (fp FuncParameter) ScriptVarName(language string) (name string) {
+	name = goIdentifier(fp.Name)
+	if callFuncScriptReservedNames[language][name] || strings.HasPrefix(name, "__") {
+		name = name + "Arg"
+	}
+	return name
+}
+
+// ScriptVarNames 参数在脚本中的变量名,逗号分隔
+func (fps FuncParameters) ScriptVarNames(language string) (names string) {
+	arr := make([]string, 0, len(fps))
+	for _, fp := range fps {
+		arr = append(arr, fp.ScriptVarName(language))
+	}
+	return strings.Join(arr, ",")
+}
+
+// ScriptType 参数在脚本中转换的类型 int、number、string、bool,其它类型不转换
+func (fp FuncParameter) ScriptType() (typ string) {
+	return normalizeFuncType(fp.Type)
+}
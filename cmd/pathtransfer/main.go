@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+const usage = `usage:
+  pathtransfer explain mapping.txt input.json   打印每个目标路径使用的转换、来源路径、原始值、转换后的值及未转换原因`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "explain":
+		err = explain(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// explain 按转换协议转换输入数据,打印追踪记录和转换结果
+func explain(args []string) (err error) {
+	if len(args) != 2 {
+		return fmt.Errorf("explain require mapping file and input file\n%s", usage)
+	}
+	mapping, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	input, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	transfers := pathtransfer.Parse(string(mapping))
+	out, trace, err := transfers.ApplyWithTrace(input)
+	if len(trace) > 0 {
+		fmt.Print(trace.Table()) // 转换出错时同样打印,便于定位
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,551 @@
+package pathtransfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+func init() {
+	gjson.AddModifier("expr", exprModifier)         // 计算表达式 @expr:{"expr":"price*qty","type":"int"}
+	gjson.AddModifier("transfer", transferModifier) // 转换引擎计算单个转换的来源值 @transfer:{"transfer":"items|flatten:Api.items"}
+	gjson.AddModifier("apply", applyModifier)       // 转换引擎转换 @apply:{"transfers":"users.*.name:*.name"}
+}
+
+// exprModifier 基于当前json计算表达式,GjsonPath 中计算转换使用
+func exprModifier(jsonStr, arg string) string {
+	argResult := gjson.Parse(arg)
+	expr, err := ParseExpr(argResult.Get("expr").String())
+	if err != nil {
+		return ""
+	}
+	value, err := expr.Eval(gjsonExprContext{doc: gjson.Parse(jsonStr)})
+	if err != nil {
+		return ""
+	}
+	rv := newResolvedValue(value)
+	if !rv.exists {
+		return ""
+	}
+	return rv.json(argResult.Get("type").String())
+}
+
+// transferModifier 基于当前json使用转换引擎计算来源值,GjsonPath 中后置处理使用
+func transferModifier(jsonStr, arg string) string {
+	transfers := Parse(gjson.Get(arg, "transfer").String())
+	if len(transfers) != 1 {
+		return ""
+	}
+	transfer := transfers[0]
+	rv, _, err := transfer.resolve(gjson.Parse(jsonStr))
+	if err != nil || !rv.exists {
+		return ""
+	}
+	return rv.json(transfer.dstType())
+}
+
+// applyModifier 基于当前json使用转换引擎转换,GjsonPath 中目标路径包含 * 时使用
+func applyModifier(jsonStr, arg string) string {
+	transfers := Parse(gjson.Get(arg, "transfers").String())
+	out, err := transfers.Apply([]byte(jsonStr))
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// resolvedValue 来源取值结果,isArray 为true时表示来源路径中的#展开后的数组,items 和目标路径中的# 一一对应
+type resolvedValue struct {
+	exists  bool
+	raw     string
+	isArray bool
+	items   []resolvedValue
+}
+
+// newResolvedValue 表达式计算结果转换为取值结果,数组按#展开处理,nil 表示不存在
+func newResolvedValue(value any) (rv resolvedValue) {
+	switch v := value.(type) {
+	case nil:
+		return resolvedValue{}
+	case []any:
+		rv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0, len(v))}
+		for _, item := range v {
+			rv.items = append(rv.items, newResolvedValue(item))
+		}
+		return rv
+	}
+	raw, err := exprValueRaw(value)
+	if err != nil {
+		return resolvedValue{}
+	}
+	return resolvedValue{exists: true, raw: raw}
+}
+
+// arrayItems 获取数组元素,来源路径未展开但值为json数组时,按json数组展开
+func (rv resolvedValue) arrayItems() (items []resolvedValue, ok bool) {
+	if rv.isArray {
+		return rv.items, true
+	}
+	result := gjson.Parse(rv.raw)
+	if !rv.exists || !result.IsArray() {
+		return nil, false
+	}
+	items = make([]resolvedValue, 0)
+	for _, item := range result.Array() {
+		items = append(items, resolvedValue{exists: true, raw: item.Raw})
+	}
+	return items, true
+}
+
+// value 转换为go值,不存在时返回nil
+func (rv resolvedValue) value() (value any) {
+	if !rv.exists {
+		return nil
+	}
+	return gjson.Parse(rv.json("")).Value()
+}
+
+// json 转换为json字符串,typ 为目标类型,不存在的数组元素输出为null
+func (rv resolvedValue) json(typ string) (s string) {
+	s, _ = rv.jsonE(typ)
+	return s
+}
+
+// jsonE 同 json,类型转换失败时保留原始值并返回第一个错误
+func (rv resolvedValue) jsonE(typ string) (s string, err error) {
+	if !rv.isArray {
+		if !rv.exists {
+			return "null", nil
+		}
+		return convertRawE(rv.raw, typ)
+	}
+	var w bytes.Buffer
+	w.WriteString("[")
+	for i, item := range rv.items {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		itemRaw, itemErr := item.jsonE(typ)
+		if err == nil {
+			err = itemErr
+		}
+		w.WriteString(itemRaw)
+	}
+	w.WriteString("]")
+	return w.String(), err
+}
+
+// convertedTypes 转换函数结果对应的json类型,用于校验转换是否成功
+var convertedTypes = map[string]gjson.Type{
+	".@tonum":    gjson.Number,
+	".@tostring": gjson.String,
+}
+
+// convertRawE 使用 DefaultTransferTypes 中的转换函数转换json值类型,null 保持不变,转换失败时保留原始值并返回错误
+func convertRawE(raw string, typ string) (newRaw string, err error) {
+	result := gjson.Parse(raw)
+	if typ == "" || result.Type == gjson.Null {
+		return raw, nil
+	}
+	transferType, ok := DefaultTransferTypes.GetByType(typ)
+	if !ok {
+		return raw, nil
+	}
+	if result.IsObject() || result.IsArray() {
+		if strings.EqualFold(typ, TransferUnit_Type_String) {
+			b, _ := json.Marshal(raw)
+			return string(b), nil
+		}
+		return raw, nil
+	}
+	converted := gjson.Get(raw, strings.TrimPrefix(transferType.ConvertFn, "."))
+	expectedType, ok := convertedTypes[transferType.ConvertFn]
+	if !converted.Exists() || !gjson.Valid(converted.Raw) || ok && converted.Type != expectedType { // 转换结果不是合法json(如 @tonum 转换 "abc")
+		err = &TypeConversionError{Value: raw, Type: typ}
+		return raw, err
+	}
+	return converted.Raw, nil
+}
+
+// convertRaw 同 convertRawE,忽略错误
+func convertRaw(raw string, typ string) (newRaw string) {
+	newRaw, _ = convertRawE(raw, typ)
+	return newRaw
+}
+
+// resolvePath 获取来源路径的值,# 逐个元素展开,保证和目标路径的数组元素一一对应,#[...] 展开前先修改数组(排序、去重、截取)
+// * 将对象的每个key对应的值按顺序展开,紧跟的 @key 获取key(数组时为下标)
+func resolvePath(current gjson.Result, segments []string) (rv resolvedValue, err error) {
+	for i, seg := range segments {
+		if !isArraySegment(seg) && seg != Path_Wildcard {
+			continue
+		}
+		parent := current
+		if i > 0 {
+			parent = current.Get(strings.Join(segments[:i], "."))
+		}
+		rest := segments[i+1:]
+		if seg == Path_Wildcard {
+			if !parent.IsObject() {
+				return resolvedValue{}, nil
+			}
+			rv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0)}
+			parent.ForEach(func(key, value gjson.Result) bool {
+				itemRv := resolvedValue{exists: true, raw: key.Raw}
+				if len(rest) == 0 || rest[0] != Path_Key {
+					itemRv, err = resolvePath(value, rest)
+				}
+				rv.items = append(rv.items, itemRv)
+				return err == nil
+			})
+			return rv, err
+		}
+		modifier, err := ParseArrayModifier(seg)
+		if err != nil {
+			return rv, err
+		}
+		if !parent.IsArray() {
+			return resolvedValue{}, nil
+		}
+		rv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0)}
+		for index, item := range modifier.Modify(parent.Array()) {
+			itemRv := resolvedValue{exists: true, raw: strconv.Itoa(index)}
+			if len(rest) == 0 || rest[0] != Path_Key {
+				itemRv, err = resolvePath(item, rest)
+				if err != nil {
+					return rv, err
+				}
+			}
+			rv.items = append(rv.items, itemRv)
+		}
+		return rv, nil
+	}
+	result := current
+	if len(segments) > 0 {
+		result = current.Get(strings.Join(segments, "."))
+	}
+	return resolvedValue{exists: result.Exists(), raw: result.Raw}, nil
+}
+
+// normalizeDstPath 标准化目标路径,删除 @this 前缀
+func normalizeDstPath(path Path) (newPath Path) {
+	s := strings.ReplaceAll(path.String(), "@this.", "")
+	s = strings.TrimPrefix(s, "@this")
+	s = strings.TrimPrefix(s, ".")
+	return Path(s)
+}
+
+// dstKeys 目标路径中 * 对应的key,key 为到 * 为止的目标路径,值为 xxx.*.@key 转换的取值结果
+type dstKeys map[string]resolvedValue
+
+// keyAt 获取 * 层级第 index 个元素的key,indexes 为外层数组下标;没有 @key 转换时使用下标作为key
+func (keys dstKeys) keyAt(pattern string, indexes []int, index int) (key string, ok bool) {
+	keysRv, has := keys[pattern]
+	if !has {
+		return strconv.Itoa(index), true
+	}
+	for _, i := range indexes {
+		items, ok := keysRv.arrayItems()
+		if !ok || i >= len(items) {
+			return "", false
+		}
+		keysRv = items[i]
+	}
+	items, ok := keysRv.arrayItems()
+	if !ok || index >= len(items) || !items[index].present() {
+		return "", false
+	}
+	return gjson.Parse(items[index].raw).String(), true
+}
+
+// isDstKeyPath 目标路径是否为 * 提供key的 xxx.*.@key
+func isDstKeyPath(segments []string) bool {
+	l := len(segments)
+	return l > 1 && segments[l-1] == Path_Key && segments[l-2] == Path_Wildcard
+}
+
+// dstWriter 将取值结果写入目标
+type dstWriter struct {
+	root any
+	keys dstKeys
+}
+
+// write 将取值结果写入目标路径,目标路径中的# 、* 依次消费来源中展开的数组,* 写入对象
+// done 为已经处理的目标路径层级,prefix 为对应的具体路径,indexes 为已经消费的数组下标
+func (w *dstWriter) write(segments []string, done []string, prefix []pathSegment, indexes []int, rv resolvedValue, typ string) {
+	for i, seg := range segments {
+		if seg != "#" && seg != Path_Wildcard {
+			continue
+		}
+		for _, key := range segments[:i] {
+			prefix = append(prefix, pathSegment{Key: unescapePathKey(key)})
+		}
+		done = append(append([]string{}, done...), segments[:i+1]...)
+		items, ok := rv.arrayItems()
+		if !ok {
+			if !rv.exists {
+				return
+			}
+			items = []resolvedValue{rv} // 单个值写入数组第一个元素
+		}
+		if jsonTreeGet(w.root, prefix) == nil {
+			var container any = &jsonArray{}
+			if seg == Path_Wildcard {
+				container = newJsonObject()
+			}
+			w.root = jsonTreeSet(w.root, prefix, container)
+		}
+		for index, item := range items {
+			itemSegment := pathSegment{Index: index, IsIndex: true}
+			if seg == Path_Wildcard {
+				key, ok := w.keys.keyAt(strings.Join(done, "."), indexes, index)
+				if !ok {
+					continue
+				}
+				itemSegment = pathSegment{Key: key}
+			}
+			itemPrefix := append(append([]pathSegment{}, prefix...), itemSegment)
+			w.write(segments[i+1:], done, itemPrefix, append(append([]int{}, indexes...), index), item, typ)
+		}
+		return
+	}
+	if !rv.exists {
+		return
+	}
+	for _, key := range segments {
+		prefix = append(prefix, pathSegment{Key: unescapePathKey(key)})
+	}
+	w.root = jsonTreeSet(w.root, prefix, jsonRaw(rv.json(typ)))
+}
+
+// mask 按条件过滤取值结果,条件为数组时逐个元素过滤
+func (rv resolvedValue) mask(cond any) (newRv resolvedValue) {
+	conds, ok := cond.([]any)
+	if !ok {
+		if exprTruthy(cond) {
+			return rv
+		}
+		return resolvedValue{}
+	}
+	items, ok := rv.arrayItems()
+	if !ok {
+		return resolvedValue{}
+	}
+	newRv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0, len(items))}
+	for i, item := range items {
+		var itemCond any
+		if i < len(conds) {
+			itemCond = conds[i]
+		}
+		newRv.items = append(newRv.items, item.mask(itemCond))
+	}
+	return newRv
+}
+
+// evalExpr 基于来源数据计算表达式
+func (t Transfer) evalExpr(exprStr string, doc gjson.Result) (value any, err error) {
+	expr, err := ParseExpr(exprStr)
+	if err != nil {
+		err = &PathSyntaxError{Transfer: t.String(), Path: Path(exprStr), Err: err}
+		return nil, err
+	}
+	value, err = expr.Eval(gjsonExprContext{doc: doc})
+	if err != nil {
+		err = errors.WithMessagef(err, "transfer %s", t.String())
+		return nil, err
+	}
+	return value, nil
+}
+
+// present 值存在且不为null,数组中任意元素存在且不为null即为存在
+func (rv resolvedValue) present() bool {
+	if !rv.exists {
+		return false
+	}
+	if !rv.isArray {
+		return gjson.Parse(rv.raw).Type != gjson.Null
+	}
+	for _, item := range rv.items {
+		if item.present() {
+			return true
+		}
+	}
+	return false
+}
+
+// coalesce 当前值不存在或为null时使用备选值,两者都是#展开的数组时逐个元素处理
+func (rv resolvedValue) coalesce(other resolvedValue) (newRv resolvedValue) {
+	if !(rv.isArray && other.isArray) {
+		if rv.present() || !other.exists { // 都为null时保留null
+			return rv
+		}
+		return other
+	}
+	size := len(rv.items)
+	if len(other.items) > size {
+		size = len(other.items)
+	}
+	newRv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0, size)}
+	for i := 0; i < size; i++ {
+		var item, otherItem resolvedValue
+		if i < len(rv.items) {
+			item = rv.items[i]
+		}
+		if i < len(other.items) {
+			otherItem = other.items[i]
+		}
+		newRv.items = append(newRv.items, item.coalesce(otherItem))
+	}
+	return newRv
+}
+
+// resolveSrc 获取来源路径的值,存在备选来源路径时依次合并,src 为第一个有值的来源路径
+func (t Transfer) resolveSrc(doc gjson.Result) (rv resolvedValue, src Path, err error) {
+	for _, path := range append([]Path{t.Src.Path}, t.Fallback...) {
+		pathRv, err := resolvePath(doc, splitPath(path))
+		if err != nil {
+			err = &PathSyntaxError{Transfer: t.String(), Path: path, Err: err}
+			return rv, "", err
+		}
+		if src == "" && pathRv.present() {
+			src = path
+		}
+		rv = rv.coalesce(pathRv)
+	}
+	if src == "" && rv.exists {
+		src = t.Src.Path
+	}
+	return rv, src, nil
+}
+
+// resolve 获取转换的来源值,存在条件时按条件过滤,src 为实际取值的来源路径(计算转换为空)
+func (t Transfer) resolve(doc gjson.Result) (rv resolvedValue, src Path, err error) {
+	if err = t.NullPolicy.Validate(); err != nil {
+		err = errors.WithMessagef(err, "transfer %s", t.String())
+		return rv, "", err
+	}
+	if t.Expr == "" {
+		rv, src, err = t.resolveSrc(doc)
+		if err != nil {
+			return rv, "", err
+		}
+	} else {
+		value, err := t.evalExpr(t.Expr, doc)
+		if err != nil {
+			return rv, "", err
+		}
+		rv = newResolvedValue(value)
+	}
+	if len(t.Pipes) > 0 {
+		value, err := t.Pipes.Call(rv.value())
+		if err != nil {
+			err = errors.WithMessagef(err, "transfer %s", t.String())
+			return rv, "", err
+		}
+		rv = newResolvedValue(value)
+	}
+	if t.When != "" {
+		cond, err := t.evalExpr(t.When, doc)
+		if err != nil {
+			return rv, "", err
+		}
+		rv = rv.mask(cond)
+	}
+	rv = rv.applyNullPolicy(t.NullPolicy, t.dstType())
+	if !rv.exists {
+		src = ""
+	}
+	return rv, src, nil
+}
+
+// Apply 转换引擎,按转换关系将输入数据转换为输出数据
+// 来源路径和目标路径中的# 按顺序一一对应,逐个元素写入;来源不存在的目标key不输出;Expr 不为空时计算表达式作为来源值;
+// When 不为空时条件为真才写入,条件结果为数组时逐个元素判断;Fallback 不为空时使用第一个存在且不为null的来源
+func (t Transfers) Apply(input []byte) (out []byte, err error) {
+	out, _, err = t.ApplyWithTrace(input)
+	return out, err
+}
+
+// ApplyWithTrace 同 Apply,同时返回每个转换的追踪记录(来源、原始值、转换后的值、未写入原因)
+func (t Transfers) ApplyWithTrace(input []byte) (out []byte, trace Trace, err error) {
+	return t.ApplyWithOption(input, ApplyOption{})
+}
+
+// ApplyOption 转换引擎选项
+type ApplyOption struct {
+	UnmappedMode          string       // 未映射来源字段的处理方式 UnmappedMode_XXX,不区分大小写,默认丢弃,不支持的值返回 ERROR_TRANSFER_UNMAPPED_MODE
+	UnmappedPathModifyFn  PathModifyFn // 透传模式下修改未映射字段的路径,为空时保持原路径
+	ErrorOnTypeConversion bool         // 类型转换失败时返回 *TypeConversionError,默认保留原始值
+	NullPolicy            NullPolicy   // 全局null处理策略,转换没有指定策略时使用
+}
+
+// ApplyWithOption 同 ApplyWithTrace,可以指定未映射来源字段、类型转换失败的处理方式
+// 所有转换的错误合并为 MultiError 返回,可以通过 errors.As 获取 *TypeConversionError、*PathSyntaxError 等
+func (t Transfers) ApplyWithOption(input []byte, option ApplyOption) (out []byte, trace Trace, err error) {
+	if err = option.NullPolicy.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if option.UnmappedMode, err = normalizeUnmappedMode(option.UnmappedMode); err != nil {
+		return nil, nil, err
+	}
+	doc := gjson.ParseBytes(input)
+	var unmapped []jsonLeaf
+	if option.UnmappedMode == UnmappedMode_Passthrough || option.UnmappedMode == UnmappedMode_Strict {
+		unmapped = t.unmappedLeaves(string(input))
+	}
+	if option.UnmappedMode == UnmappedMode_Strict && len(unmapped) > 0 {
+		err = errors.WithMessagef(ERROR_TRANSFER_UNMAPPED_FIELD, "%s", strings.Join(uniqueLeafPaths(unmapped), ","))
+		return nil, nil, err
+	}
+	writer := &dstWriter{keys: dstKeys{}}
+	rvs := make([]resolvedValue, 0, len(t))
+	trace = make(Trace, 0, len(t))
+	var errs MultiError
+	for i := range t {
+		transfer := t[i]
+		if transfer.NullPolicy == "" {
+			transfer.NullPolicy = option.NullPolicy
+		}
+		rv, src, err := transfer.resolve(doc)
+		if err != nil { // 记录错误,继续处理其它转换,最后一起返回
+			errs.Append(err)
+			rvs = append(rvs, resolvedValue{})
+			trace = append(trace, TraceItem{Dst: transfer.Dst.Path, Transfer: transfer.String(), Reason: TraceReason_Error, Error: err.Error()})
+			continue
+		}
+		rvs = append(rvs, rv)
+		traceItem := newTraceItem(transfer, src, rv)
+		trace = append(trace, traceItem)
+		if option.ErrorOnTypeConversion && traceItem.Reason == TraceReason_TypeError {
+			_, err = rv.jsonE(transfer.dstType())
+			errs.Append(transfer.withTypeConversionError(err))
+		}
+		dstSegments := splitPath(normalizeDstPath(transfer.Dst.Path))
+		if isDstKeyPath(dstSegments) { // 先收集 * 的key,再写入
+			writer.keys[strings.Join(dstSegments[:len(dstSegments)-1], ".")] = rv
+		}
+	}
+	if len(errs) > 0 {
+		return nil, trace, errs
+	}
+	for i, transfer := range t {
+		dstSegments := splitPath(normalizeDstPath(transfer.Dst.Path))
+		if isDstKeyPath(dstSegments) {
+			continue
+		}
+		writer.write(dstSegments, nil, nil, nil, rvs[i], transfer.dstType())
+	}
+	root := writer.root
+	if option.UnmappedMode == UnmappedMode_Passthrough {
+		root, trace = passthroughLeaves(root, trace, unmapped, option.UnmappedPathModifyFn)
+	}
+	if root == nil {
+		root = newJsonObject()
+	}
+	out = []byte(jsonTreeString(root))
+	return out, trace, nil
+}
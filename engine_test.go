@@ -0,0 +1,158 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+func TestApply(t *testing.T) {
+	data := `{"user":{"first_name":"San","last_name":"Zhang","age":"18"},"items":[{"price":"2.5","qty":2,"tags":["a","b"]},{"price":10,"qty":3,"remark":"gift"}]}`
+	t.Run("path", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+user.first_name:Api.user.firstName
+user.age:Api.user.age@int
+items.#.price:Api.items.#.price@number
+items.#.remark:Api.items.#.remark
+items.#.tags.#:Api.items.#.tags.#
+user.nickname:Api.user.nickname
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		expected := `{"Api":{"user":{"firstName":"San","age":18},"items":[{"price":2.5,"tags":["a","b"]},{"price":10,"remark":"gift"}]}}`
+		require.JSONEq(t, expected, string(out))
+	})
+	t.Run("expr", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+="${user.first_name} ${user.last_name}":user.fullName
+=concat(upper(user.last_name),"-",user.age+1):user.code
+=user.age*2:user.double@int
+=items.#.price*items.#.qty:items.#.amount@number
+=round(items.#.price*items.#.qty/3,2):items.#.avg
+=items.0.price+items.1.price+items.2.price:total
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		expected := `{"user":{"fullName":"San Zhang","code":"ZHANG-19","double":36},"items":[{"amount":5,"avg":1.67},{"amount":30,"avg":10}]}`
+		require.JSONEq(t, expected, string(out))
+	})
+	t.Run("gjson path", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+="${user.first_name} ${user.last_name}":user.fullName
+=user.age*2:user.double@int
+user.age:user.age@int
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		gjsonOut := gjson.Get(data, transfers.GjsonPath()).String()
+		require.JSONEq(t, string(out), gjsonOut)
+	})
+	t.Run("root array", func(t *testing.T) {
+		transfers := pathtransfer.ToGoTypeTransfer([]user{})
+		out, err := transfers.Apply([]byte(`[{"name":"张三","userId":"1"},{"name":"李四"}]`))
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"name":"张三","userId":1},{"name":"李四"}]`, string(out))
+	})
+}
+
+func TestParseExprTransfer(t *testing.T) {
+	line := `=concat(user.firstName,":",user.lastName):Api.user.fullName@string`
+	transfers := pathtransfer.Parse(line)
+	require.Equal(t, 1, len(transfers))
+	require.Equal(t, `concat(user.firstName,":",user.lastName)`, transfers[0].Expr)
+	require.Equal(t, pathtransfer.Path("Api.user.fullName"), transfers[0].Dst.Path)
+	require.Equal(t, "string", transfers[0].Dst.Type)
+	require.Equal(t, line+"\n", transfers.String())
+	require.Equal(t, []pathtransfer.Path{"user.firstName", "user.lastName"}, transfers[0].SrcPaths())
+}
+
+func TestExpr(t *testing.T) {
+	doc := []byte(`{"a":2,"b":"3","s":"x","arr":[1,2,3],"vip":true}`)
+	cases := map[string]any{
+		`a+b*2`:             float64(8),
+		`(a+b)*2`:           float64(10),
+		`s+a`:               "x2",
+		`a==2 && s=="x"`:    true,
+		`!vip || a>b`:       false,
+		`if(a>=2,"y","n")`:  "y",
+		`len(arr)`:          float64(3),
+		`arr*a`:             []any{float64(2), float64(4), float64(6)},
+		`missing*a`:         nil,
+		`-a%3`:              float64(-2),
+		`"${s}-${a+1}"`:     "x-3",
+		"`s`":               "x",
+		`lower("ABC")+null`: "abc",
+	}
+	for exprStr, expected := range cases {
+		expr, err := pathtransfer.ParseExpr(exprStr)
+		require.NoError(t, err, exprStr)
+		value, err := expr.EvalJson(doc)
+		require.NoError(t, err, exprStr)
+		require.Equal(t, expected, value, exprStr)
+	}
+	_, err := pathtransfer.ParseExpr(`a+`)
+	require.ErrorIs(t, err, pathtransfer.ERROR_EXPR_SYNTAX)
+}
+
+func TestApplyWhen(t *testing.T) {
+	data := `{"type":"vip","order":{"price":100,"vipPrice":80,"discount":"0.8"},"items":[{"type":"vip","price":1},{"type":"normal","price":2}]}`
+	transfers := pathtransfer.Parse(`
+order.discount:Api.order.discount@number when type=="vip"
+order.vipPrice:Api.order.price when type=='vip'
+order.price:Api.order.price when type!="vip"
+items.#.price:Api.items.#.vipPrice when items.#.type=="vip"
+items.#.price:Api.items.#.price
+=order.price*0.5:Api.order.half when order.price>=100 && type=="vip"
+	`)
+	require.Equal(t, `type=="vip"`, transfers[0].When)
+	require.Equal(t, `order.discount:Api.order.discount@number when type=="vip"`, transfers[0].String())
+	out, err := transfers.Apply([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{"order":{"discount":0.8,"price":80,"half":50},"items":[{"vipPrice":1,"price":1},{"price":2}]}}`
+	require.JSONEq(t, expected, string(out))
+
+	// GjsonPath 中按元素过滤的值输出为null,只比较标量条件
+	scalar := transfers[:3]
+	gjsonOut := gjson.Get(data, scalar.GjsonPath()).String()
+	require.JSONEq(t, `{"Api":{"order":{"discount":0.8,"price":80}}}`, gjsonOut)
+
+	normal := `{"type":"normal","order":{"price":100,"vipPrice":80,"discount":"0.8"}}`
+	out, err = transfers.Apply([]byte(normal))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Api":{"order":{"price":100}}}`, string(out))
+}
+
+func TestApplyCoalesce(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+user.user_name??user.userName??user.name:Api.name
+user.age@int??user.userAge:Api.age@int
+items.#.title??items.#.name:Api.items.#.title
+	`)
+	require.Equal(t, []pathtransfer.Path{"user.userName", "user.name"}, transfers[0].Fallback)
+	require.Equal(t, "user.user_name??user.userName??user.name:Api.name", transfers[0].String())
+	require.Equal(t, "int", transfers[1].Src.Type)
+
+	data := `{"user":{"user_name":null,"userName":"张三","name":"zhangsan","userAge":"18"},"items":[{"title":"a"},{"title":null,"name":"b"},{}]}`
+	out, trace, err := transfers.ApplyWithTrace([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{"name":"张三","age":18,"items":[{"title":"a"},{"title":"b"}]}}`
+	require.JSONEq(t, expected, string(out))
+
+	item, ok := trace.GetByDst("Api.name")
+	require.True(t, ok)
+	require.Equal(t, pathtransfer.Path("user.userName"), item.Src)
+	item, _ = trace.GetByDst("Api.age")
+	require.Equal(t, pathtransfer.Path("user.userAge"), item.Src)
+	item, _ = trace.GetByDst("Api.items.#.title")
+	require.Equal(t, pathtransfer.Path("items.#.title"), item.Src)
+
+	gjsonOut := gjson.Get(data, transfers[:2].GjsonPath()).String()
+	require.JSONEq(t, `{"Api":{"name":"张三","age":18}}`, gjsonOut)
+
+	out, trace, err = transfers[:1].ApplyWithTrace([]byte(`{"user":{"user_name":null}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Api":{"name":null}}`, string(out))
+	require.Equal(t, pathtransfer.Path("user.user_name"), trace[0].Src)
+}
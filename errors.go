@@ -0,0 +1,147 @@
+package pathtransfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ERROR_TRANSFER_TYPE_CONVERSION  = errors.New("type conversion failed")
+	ERROR_TRANSFER_FUNC_ARG_MISSING = errors.New("missing transfer func arg")
+	ERROR_TRANSFER_PATH_SYNTAX      = errors.New("path syntax error")
+)
+
+// MissingArgError 函数入参缺失,Path 为入参在输入数据中的路径
+type MissingArgError struct {
+	Transfer string `json:"transfer"`
+	FuncName string `json:"funcName"` // package.FuncName,不含 func. 命名空间,和 FuncCallError、FuncCallResult 一致
+	Path     Path   `json:"path"`
+}
+
+func (e *MissingArgError) Error() string {
+	return fmt.Sprintf("%s: func %s arg %s", ERROR_TRANSFER_FUNC_ARG_MISSING, e.FuncName, e.Path)
+}
+
+func (e *MissingArgError) Unwrap() error {
+	return ERROR_TRANSFER_FUNC_ARG_MISSING
+}
+
+// TypeConversionError 类型转换失败,Path 为目标路径,Value 为来源原始值
+type TypeConversionError struct {
+	Transfer string `json:"transfer"`
+	Path     Path   `json:"path"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+}
+
+func (e *TypeConversionError) Error() string {
+	var w strings.Builder
+	w.WriteString(ERROR_TRANSFER_TYPE_CONVERSION.Error())
+	w.WriteString(": ")
+	if e.Path != "" {
+		w.WriteString(fmt.Sprintf("path %s ", e.Path))
+	}
+	w.WriteString(fmt.Sprintf("value %s to %s", e.Value, e.Type))
+	if e.Transfer != "" {
+		w.WriteString(fmt.Sprintf(" (transfer %s)", e.Transfer))
+	}
+	return w.String()
+}
+
+func (e *TypeConversionError) Unwrap() error {
+	return ERROR_TRANSFER_TYPE_CONVERSION
+}
+
+// withTypeConversionError 类型转换错误补充转换关系、目标路径
+func (t Transfer) withTypeConversionError(err error) error {
+	var typeErr *TypeConversionError
+	if errors.As(err, &typeErr) {
+		typeErr.Transfer, typeErr.Path = t.String(), t.Dst.Path
+	}
+	return err
+}
+
+// PathSyntaxError 路径、表达式格式错误,Err 为具体原因
+type PathSyntaxError struct {
+	Transfer string `json:"transfer"`
+	Path     Path   `json:"path"`
+	Err      error  `json:"-"`
+}
+
+func (e *PathSyntaxError) Error() string {
+	s := fmt.Sprintf("%s: %s", ERROR_TRANSFER_PATH_SYNTAX, e.Path)
+	if e.Transfer != "" {
+		s = fmt.Sprintf("%s (transfer %s)", s, e.Transfer)
+	}
+	if e.Err != nil {
+		s = fmt.Sprintf("%s: %s", s, e.Err.Error())
+	}
+	return s
+}
+
+// Is 匹配 ERROR_TRANSFER_PATH_SYNTAX,Unwrap 匹配具体原因
+func (e *PathSyntaxError) Is(target error) bool {
+	return target == ERROR_TRANSFER_PATH_SYNTAX
+}
+
+func (e *PathSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// FuncCallError 转换函数执行失败,Transfer 为函数的入参转换关系(多个以换行分隔),Path 为函数路径(func.package.FuncName)
+type FuncCallError struct {
+	Transfer string `json:"transfer"`
+	FuncName string `json:"funcName"` // package.FuncName,不含 func. 命名空间
+	Path     Path   `json:"path"`
+	Input    string `json:"input"`
+	Err      error  `json:"-"`
+}
+
+func (e *FuncCallError) Error() string {
+	return fmt.Sprintf("call func %s with input %s: %s", e.FuncName, e.Input, e.Err)
+}
+
+func (e *FuncCallError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError 多个错误,如 Apply 中每个转换的错误,便于按字段返回给调用方
+type MultiError []error
+
+func (me MultiError) Error() string {
+	arr := make([]string, 0, len(me))
+	for _, err := range me {
+		arr = append(arr, err.Error())
+	}
+	return strings.Join(arr, "; ")
+}
+
+// Unwrap 支持 errors.Is/errors.As 匹配其中任意一个错误
+func (me MultiError) Unwrap() []error {
+	return me
+}
+
+// Append 增加错误,nil 忽略,MultiError 展开
+func (me *MultiError) Append(errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var multi MultiError
+		if errors.As(err, &multi) {
+			*me = append(*me, multi...)
+			continue
+		}
+		*me = append(*me, err)
+	}
+}
+
+// ErrorOrNil 没有错误时返回nil
+func (me MultiError) ErrorOrNil() error {
+	if len(me) == 0 {
+		return nil
+	}
+	return me
+}
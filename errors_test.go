@@ -0,0 +1,78 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestApplyErrors(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+user.age:Api.age@int
+user.level:Api.level@int
+items.#[top=1].name:Api.names
+=price*(qty:Api.amount
+user.name:Api.name
+	`)
+	data := `{"user":{"age":"abc","level":"x","name":"张三"},"items":[{"name":"a"}]}`
+	_, trace, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{ErrorOnTypeConversion: true})
+	require.Error(t, err)
+	require.Len(t, trace, len(transfers))
+
+	var multi pathtransfer.MultiError
+	require.True(t, errors.As(err, &multi))
+	require.Len(t, multi, 4)
+
+	var typeErr *pathtransfer.TypeConversionError
+	require.True(t, errors.As(multi[0], &typeErr))
+	require.Equal(t, pathtransfer.TypeConversionError{Transfer: "user.age:Api.age@int", Path: "Api.age", Value: `"abc"`, Type: "int"}, *typeErr)
+
+	var syntaxErr *pathtransfer.PathSyntaxError
+	require.True(t, errors.As(multi[2], &syntaxErr))
+	require.Equal(t, pathtransfer.Path("items.#[top=1].name"), syntaxErr.Path)
+	require.ErrorIs(t, multi[2], pathtransfer.ERROR_TRANSFER_ARRAY_MODIFIER)
+	require.ErrorIs(t, multi[3], pathtransfer.ERROR_EXPR_SYNTAX)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PATH_SYNTAX)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_TYPE_CONVERSION)
+
+	out, err := transfers[:2].Apply([]byte(data))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Api":{"age":"abc","level":"x"}}`, string(out))
+}
+
+func TestCallTransferFuncErrors(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.SetLimit.input.index@int:data.pagination.index
+func.SetLimit.input.size@int:data.pagination.size
+func.SetLimit.output.offset@int:data.limit.offset
+	`)
+	_, err := pathtransfer.CallTransferFunc(transfers, []byte(`{"data":{}}`), nil)
+	var multi pathtransfer.MultiError
+	require.True(t, errors.As(err, &multi))
+	require.Len(t, multi, 2)
+	var missingErr *pathtransfer.MissingArgError
+	require.True(t, errors.As(multi[1], &missingErr))
+	require.Equal(t, pathtransfer.Path("data.pagination.size"), missingErr.Path)
+	require.Equal(t, "SetLimit", missingErr.FuncName)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_ARG_MISSING)
+
+	data := []byte(`{"data":{"pagination":{"index":1,"size":20}}}`)
+	_, err = pathtransfer.CallTransferFunc(transfers, data, func(funcname string, input []byte) (out []byte, err error) {
+		return nil, errors.New("db timeout")
+	})
+	var callErr *pathtransfer.FuncCallError
+	require.True(t, errors.As(err, &callErr))
+	require.Equal(t, missingErr.FuncName, callErr.FuncName)
+	require.Equal(t, pathtransfer.Path("func.SetLimit"), callErr.Path)
+	require.Equal(t, "func.SetLimit.input.index@int:data.pagination.index\nfunc.SetLimit.input.size@int:data.pagination.size", callErr.Transfer)
+	require.EqualError(t, callErr.Err, "db timeout")
+
+	_, err = pathtransfer.CallTransferFunc(transfers, data, nil)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_NAME_NOT_FOUND)
+
+	_, err = pathtransfer.TransferUnit{Path: "func.SetLimit.index"}.FuncParameter()
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PATH_DIRECTION_MISSING)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PATH_SYNTAX)
+}
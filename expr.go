@@ -0,0 +1,752 @@
+package pathtransfer
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_EXPR_SYNTAX = errors.New("expr syntax error")
+)
+
+// Expr 计算表达式,用于多个来源路径计算一个目标值,转换协议中以 = 开头,如:
+//
+//	=price*qty:Api.order.amount@int
+//	="${firstName} ${lastName}":Api.user.fullName
+//	=concat(user.firstName," ",user.lastName):Api.user.fullName
+//
+// 语法:
+//   - 路径: user.firstName、items.#.price(数组逐个元素计算)、`user-name`(特殊字符使用反引号包裹)
+//   - 字面量: 1.5、"abc"、'abc'(字符串中的 ${expr} 为模板插值)、true、false、null
+//   - 运算: + - * / % == != > >= < <= && || ! 括号,+ 两边都能转为数字时相加,否则为字符串拼接
+//   - 函数: concat、if、upper、lower、len、round,可通过 RegisterExprFunc 扩展
+type Expr struct {
+	source string
+	root   exprNode
+}
+
+// ExprContext 表达式取值上下文
+type ExprContext interface {
+	Get(path Path) (value any)
+}
+
+// gjsonExprContext 从json文档中取值,路径不存在时返回nil
+type gjsonExprContext struct {
+	doc gjson.Result
+}
+
+func (ctx gjsonExprContext) Get(path Path) (value any) {
+	result := ctx.doc.Get(path.String())
+	if !result.Exists() {
+		return nil
+	}
+	return result.Value()
+}
+
+// ParseExpr 解析表达式
+func ParseExpr(s string) (expr *Expr, err error) {
+	p := &exprParser{src: s}
+	err = p.tokenize()
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != exprToken_EOF {
+		err = errors.WithMessagef(ERROR_EXPR_SYNTAX, "unexpected %q in %s", p.peek().text, s)
+		return nil, err
+	}
+	expr = &Expr{source: s, root: root}
+	return expr, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Eval 计算表达式
+func (e *Expr) Eval(ctx ExprContext) (value any, err error) {
+	return e.root.eval(ctx)
+}
+
+// EvalJson 基于json文档计算表达式
+func (e *Expr) EvalJson(doc []byte) (value any, err error) {
+	return e.Eval(gjsonExprContext{doc: gjson.ParseBytes(doc)})
+}
+
+// Paths 表达式引用的所有路径
+func (e *Expr) Paths() (paths []Path) {
+	paths = make([]Path, 0)
+	e.root.walk(func(node exprNode) {
+		if pathNode, ok := node.(exprPathNode); ok {
+			for _, exists := range paths {
+				if exists == pathNode.path {
+					return
+				}
+			}
+			paths = append(paths, pathNode.path)
+		}
+	})
+	return paths
+}
+
+// ExprFunc 表达式函数
+type ExprFunc func(args ...any) (value any, err error)
+
+// exprFuncDef 表达式函数定义,broadcast 为true时参数中的数组按元素逐个调用
+type exprFuncDef struct {
+	fn        ExprFunc
+	broadcast bool
+}
+
+// exprFuncs 表达式可用的函数,通过 RegisterExprFunc 扩展
+var exprFuncs = map[string]exprFuncDef{
+	"concat": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		var w strings.Builder
+		for _, arg := range args {
+			w.WriteString(cast.ToString(arg))
+		}
+		return w.String(), nil
+	}},
+	"if": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		if len(args) != 3 {
+			return nil, errors.Errorf("if require 3 args,got:%d", len(args))
+		}
+		if exprTruthy(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	}},
+	"coalesce": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	}},
+	"upper": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		return strings.ToUpper(cast.ToString(exprFirstArg(args))), nil
+	}},
+	"lower": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		return strings.ToLower(cast.ToString(exprFirstArg(args))), nil
+	}},
+	"round": {broadcast: true, fn: func(args ...any) (value any, err error) {
+		f, err := cast.ToFloat64E(exprFirstArg(args))
+		if err != nil {
+			return nil, err
+		}
+		precision := 0
+		if len(args) > 1 {
+			precision = cast.ToInt(args[1])
+		}
+		pow := math.Pow(10, float64(precision))
+		return math.Round(f*pow) / pow, nil
+	}},
+	"len": {fn: func(args ...any) (value any, err error) {
+		switch v := exprFirstArg(args).(type) {
+		case nil:
+			return float64(0), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		default:
+			return float64(len([]rune(cast.ToString(v)))), nil
+		}
+	}},
+}
+
+// exprFuncsMu 保护 exprFuncs,注册和表达式计算可能并发
+var exprFuncsMu sync.RWMutex
+
+// RegisterExprFunc 注册表达式函数,broadcast 为true时参数中的数组按元素逐个调用
+func RegisterExprFunc(name string, broadcast bool, fn ExprFunc) {
+	exprFuncsMu.Lock()
+	defer exprFuncsMu.Unlock()
+	exprFuncs[name] = exprFuncDef{fn: fn, broadcast: broadcast}
+}
+
+func exprFunc(name string) (def exprFuncDef, ok bool) {
+	exprFuncsMu.RLock()
+	defer exprFuncsMu.RUnlock()
+	def, ok = exprFuncs[name]
+	return def, ok
+}
+
+func exprFirstArg(args []any) (arg any) {
+	if len(args) == 0 {
+		return nil
+	}
+	return args[0]
+}
+
+// exprTruthy 判断值是否为真: nil、false、0、空字符串、空数组为假
+func exprTruthy(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case float64:
+		return val != 0
+	case string:
+		return val != ""
+	case []any:
+		return len(val) > 0
+	}
+	return true
+}
+
+// exprBroadcast 参数中存在数组时,按元素逐个调用fn,结果为数组
+func exprBroadcast(args []any, fn func(args []any) (value any, err error)) (value any, err error) {
+	size := -1
+	for _, arg := range args {
+		if arr, ok := arg.([]any); ok && (size < 0 || len(arr) > size) {
+			size = len(arr)
+		}
+	}
+	if size < 0 {
+		return fn(args)
+	}
+	values := make([]any, 0, size)
+	for i := 0; i < size; i++ {
+		itemArgs := make([]any, len(args))
+		for j, arg := range args {
+			itemArgs[j] = arg
+			if arr, ok := arg.([]any); ok {
+				itemArgs[j] = nil
+				if i < len(arr) {
+					itemArgs[j] = arr[i]
+				}
+			}
+		}
+		item, err := exprBroadcast(itemArgs, fn)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, item)
+	}
+	return values, nil
+}
+
+type exprNode interface {
+	eval(ctx ExprContext) (value any, err error)
+	walk(fn func(node exprNode))
+}
+
+type exprLiteralNode struct {
+	value any
+}
+
+func (n exprLiteralNode) eval(ctx ExprContext) (value any, err error) {
+	return n.value, nil
+}
+
+func (n exprLiteralNode) walk(fn func(node exprNode)) {
+	fn(n)
+}
+
+type exprPathNode struct {
+	path Path
+}
+
+func (n exprPathNode) eval(ctx ExprContext) (value any, err error) {
+	return ctx.Get(n.path), nil
+}
+
+func (n exprPathNode) walk(fn func(node exprNode)) {
+	fn(n)
+}
+
+// exprTemplateNode 字符串模板,parts 依次拼接
+type exprTemplateNode struct {
+	parts []exprNode
+}
+
+func (n exprTemplateNode) eval(ctx ExprContext) (value any, err error) {
+	args := make([]any, 0, len(n.parts))
+	for _, part := range n.parts {
+		v, err := part.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return exprBroadcast(args, func(args []any) (value any, err error) {
+		def, _ := exprFunc("concat")
+		return def.fn(args...)
+	})
+}
+
+func (n exprTemplateNode) walk(fn func(node exprNode)) {
+	fn(n)
+	for _, part := range n.parts {
+		part.walk(fn)
+	}
+}
+
+type exprUnaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n exprUnaryNode) eval(ctx ExprContext) (value any, err error) {
+	v, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return exprBroadcast([]any{v}, func(args []any) (value any, err error) {
+		switch n.op {
+		case "!":
+			return !exprTruthy(args[0]), nil
+		case "-":
+			if args[0] == nil {
+				return nil, nil
+			}
+			f, err := cast.ToFloat64E(args[0])
+			if err != nil {
+				return nil, errors.Errorf("expr operator - require number,got:%v", args[0])
+			}
+			return -f, nil
+		}
+		return nil, errors.Errorf("unknown unary operator:%s", n.op)
+	})
+}
+
+func (n exprUnaryNode) walk(fn func(node exprNode)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+type exprBinaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n exprBinaryNode) eval(ctx ExprContext) (value any, err error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op { // 短路求值(非数组情况)
+	case "&&":
+		if _, ok := left.([]any); !ok && !exprTruthy(left) {
+			return false, nil
+		}
+	case "||":
+		if _, ok := left.([]any); !ok && exprTruthy(left) {
+			return true, nil
+		}
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return exprBroadcast([]any{left, right}, func(args []any) (value any, err error) {
+		return exprBinaryOperate(n.op, args[0], args[1])
+	})
+}
+
+func (n exprBinaryNode) walk(fn func(node exprNode)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+func exprToNumber(v any) (f float64, ok bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case bool:
+		return cast.ToFloat64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func exprBinaryOperate(op string, left any, right any) (value any, err error) {
+	switch op {
+	case "&&":
+		return exprTruthy(left) && exprTruthy(right), nil
+	case "||":
+		return exprTruthy(left) || exprTruthy(right), nil
+	case "==", "!=":
+		equal := false
+		lf, lok := exprToNumber(left)
+		rf, rok := exprToNumber(right)
+		switch {
+		case left == nil || right == nil:
+			equal = left == nil && right == nil
+		case lok && rok:
+			equal = lf == rf
+		default:
+			equal = cast.ToString(left) == cast.ToString(right)
+		}
+		if op == "!=" {
+			return !equal, nil
+		}
+		return equal, nil
+	case ">", ">=", "<", "<=":
+		if left == nil || right == nil {
+			return false, nil
+		}
+		cmp := 0
+		lf, lok := exprToNumber(left)
+		rf, rok := exprToNumber(right)
+		if lok && rok {
+			if lf < rf {
+				cmp = -1
+			} else if lf > rf {
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(cast.ToString(left), cast.ToString(right))
+		}
+		switch op {
+		case ">":
+			return cmp > 0, nil
+		case ">=":
+			return cmp >= 0, nil
+		case "<":
+			return cmp < 0, nil
+		}
+		return cmp <= 0, nil
+	case "+":
+		_, lIsString := left.(string)
+		_, rIsString := right.(string)
+		_, lok := exprToNumber(left)
+		_, rok := exprToNumber(right)
+		if (lIsString || rIsString) && !(lok && rok) {
+			return cast.ToString(left) + cast.ToString(right), nil
+		}
+	}
+	// 算术运算,任一操作数不存在时结果不存在
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	lf, lok := exprToNumber(left)
+	rf, rok := exprToNumber(right)
+	if !lok || !rok {
+		err = errors.Errorf("expr operator %s require number,got:%v,%v", op, left, right)
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/", "%":
+		if rf == 0 {
+			return nil, errors.Errorf("expr division by zero")
+		}
+		if op == "%" {
+			return math.Mod(lf, rf), nil
+		}
+		return lf / rf, nil
+	}
+	return nil, errors.Errorf("unknown operator:%s", op)
+}
+
+type exprCallNode struct {
+	name string
+	args []exprNode
+}
+
+func (n exprCallNode) eval(ctx ExprContext) (value any, err error) {
+	def, ok := exprFunc(n.name)
+	if !ok {
+		return nil, errors.Errorf("expr func %s not found", n.name)
+	}
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if !def.broadcast {
+		return def.fn(args...)
+	}
+	return exprBroadcast(args, func(args []any) (value any, err error) {
+		return def.fn(args...)
+	})
+}
+
+func (n exprCallNode) walk(fn func(node exprNode)) {
+	fn(n)
+	for _, arg := range n.args {
+		arg.walk(fn)
+	}
+}
+
+const (
+	exprToken_EOF    = "eof"
+	exprToken_Number = "number"
+	exprToken_String = "string"
+	exprToken_Ident  = "ident"
+	exprToken_Op     = "op"
+)
+
+type exprToken struct {
+	kind string
+	text string
+}
+
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	pos    int
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+func isExprIdentChar(r rune, first bool) bool {
+	if unicode.IsLetter(r) || r == '_' || r == '@' {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '.' || r == '#')
+}
+
+func (p *exprParser) tokenize() (err error) {
+	runes := []rune(p.src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: exprToken_Number, text: string(runes[i:j])})
+			i = j
+		case r == '"' || r == '\'' || r == '`':
+			j := i + 1
+			var w strings.Builder
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' && j+1 < len(runes) && r != '`' {
+					j++
+				}
+				w.WriteRune(runes[j])
+				j++
+			}
+			if j >= len(runes) {
+				return errors.WithMessagef(ERROR_EXPR_SYNTAX, "unterminated string in %s", p.src)
+			}
+			kind := exprToken_String
+			if r == '`' {
+				kind = exprToken_Ident // 反引号包裹的为路径
+			}
+			p.tokens = append(p.tokens, exprToken{kind: kind, text: w.String()})
+			i = j + 1
+		case isExprIdentChar(r, true):
+			j := i
+			for j < len(runes) && (isExprIdentChar(runes[j], false) || runes[j] == '\\' && j+1 < len(runes)) {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			p.tokens = append(p.tokens, exprToken{kind: exprToken_Ident, text: string(runes[i:j])})
+			i = j
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					p.tokens = append(p.tokens, exprToken{kind: exprToken_Op, text: op})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return errors.WithMessagef(ERROR_EXPR_SYNTAX, "unexpected char %q in %s", r, p.src)
+			}
+		}
+	}
+	p.tokens = append(p.tokens, exprToken{kind: exprToken_EOF})
+	return nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != exprToken_EOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) expectOp(op string) (err error) {
+	t := p.next()
+	if t.kind != exprToken_Op || t.text != op {
+		return errors.WithMessagef(ERROR_EXPR_SYNTAX, "expected %q,got %q in %s", op, t.text, p.src)
+	}
+	return nil
+}
+
+// exprPrecedence 二元运算优先级,数字越大优先级越高
+var exprPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	">": 4, ">=": 4, "<": 4, "<=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (p *exprParser) parseBinary(minPrecedence int) (node exprNode, err error) {
+	node, err = p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		precedence, ok := exprPrecedence[t.text]
+		if t.kind != exprToken_Op || !ok || precedence <= minPrecedence {
+			return node, nil
+		}
+		p.next()
+		right, err := p.parseBinary(precedence)
+		if err != nil {
+			return nil, err
+		}
+		node = exprBinaryNode{op: t.text, left: node, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (node exprNode, err error) {
+	t := p.peek()
+	if t.kind == exprToken_Op && (t.text == "!" || t.text == "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnaryNode{op: t.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node exprNode, err error) {
+	t := p.next()
+	switch t.kind {
+	case exprToken_Number:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.WithMessagef(ERROR_EXPR_SYNTAX, "invalid number %s in %s", t.text, p.src)
+		}
+		return exprLiteralNode{value: f}, nil
+	case exprToken_String:
+		return parseExprTemplate(t.text)
+	case exprToken_Ident:
+		switch t.text {
+		case "true":
+			return exprLiteralNode{value: true}, nil
+		case "false":
+			return exprLiteralNode{value: false}, nil
+		case "null":
+			return exprLiteralNode{value: nil}, nil
+		}
+		if next := p.peek(); next.kind == exprToken_Op && next.text == "(" {
+			return p.parseCall(t.text)
+		}
+		return exprPathNode{path: Path(t.text)}, nil
+	case exprToken_Op:
+		if t.text == "(" {
+			node, err = p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	return nil, errors.WithMessagef(ERROR_EXPR_SYNTAX, "unexpected %q in %s", t.text, p.src)
+}
+
+func (p *exprParser) parseCall(name string) (node exprNode, err error) {
+	p.next() // (
+	call := exprCallNode{name: name, args: make([]exprNode, 0)}
+	if next := p.peek(); next.kind == exprToken_Op && next.text == ")" {
+		p.next()
+		return call, nil
+	}
+	for {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		t := p.next()
+		if t.kind == exprToken_Op && t.text == ")" {
+			return call, nil
+		}
+		if t.kind != exprToken_Op || t.text != "," {
+			return nil, errors.WithMessagef(ERROR_EXPR_SYNTAX, "expected , or ) in %s", p.src)
+		}
+	}
+}
+
+// parseExprTemplate 解析字符串中的 ${expr} 模板插值,无插值时为字符串字面量
+func parseExprTemplate(s string) (node exprNode, err error) {
+	if !strings.Contains(s, "${") {
+		return exprLiteralNode{value: s}, nil
+	}
+	parts := make([]exprNode, 0)
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return nil, errors.WithMessagef(ERROR_EXPR_SYNTAX, "unterminated ${ in %s", s)
+		}
+		end += start
+		if start > 0 {
+			parts = append(parts, exprLiteralNode{value: s[:start]})
+		}
+		sub, err := ParseExpr(s[start+2 : end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, sub.root)
+		s = s[end+1:]
+	}
+	if s != "" {
+		parts = append(parts, exprLiteralNode{value: s})
+	}
+	return exprTemplateNode{parts: parts}, nil
+}
+
+// exprValueRaw 表达式结果转换为json
+func exprValueRaw(value any) (raw string, err error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,212 @@
+package pathtransfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_FUNC_ERROR_MODE = errors.New("unknown func error mode")
+)
+
+const (
+	FuncErrorMode_Fail    = "fail"    // 函数出错时整体失败,默认
+	FuncErrorMode_Skip    = "skip"    // 函数出错时跳过,输入保持不变
+	FuncErrorMode_Default = "default" // 函数出错时使用默认出参
+)
+
+const (
+	FuncCallStatus_Ran     = "ran"     // 执行成功
+	FuncCallStatus_Skipped = "skipped" // 出错后跳过
+	FuncCallStatus_Default = "default" // 出错后使用默认出参
+	FuncCallStatus_Failed  = "failed"  // 出错后整体失败
+)
+
+// FuncErrorPolicy 函数出错(入参缺失、执行出错)时的处理策略
+type FuncErrorPolicy struct {
+	Mode string `json:"mode"` // FuncErrorMode_XXX,不区分大小写,为空时为 FuncErrorMode_Fail
+	// Retry 执行出错时的重试次数,入参缺失不重试,小于0时为0
+	Retry int `json:"retry,omitempty"`
+	// Defaults Mode 为 FuncErrorMode_Default 时的出参,key 为出参名(本地数据格式),按出参转换关系写入输入数据
+	Defaults map[string]any `json:"defaults,omitempty"`
+}
+
+// CallFuncOption CallTransferFuncWithOption 的选项
+type CallFuncOption struct {
+	// Closure 执行函数,为nil时使用 DefaultFuncRegistry
+	Closure func(funcname string, input []byte) (out []byte, err error)
+	// Policy 全局出错策略,Policies 中没有单独设置的函数使用
+	Policy FuncErrorPolicy
+	// Policies 按函数名(package.FuncName)设置出错策略
+	Policies map[string]FuncErrorPolicy
+}
+
+// normalize 统一 Mode 大小写、补充默认值,不支持的 Mode 返回错误
+func (policy FuncErrorPolicy) normalize() (normalized FuncErrorPolicy, err error) {
+	normalized = policy
+	normalized.Mode = strings.ToLower(strings.TrimSpace(policy.Mode))
+	switch normalized.Mode {
+	case "":
+		normalized.Mode = FuncErrorMode_Fail
+	case FuncErrorMode_Fail, FuncErrorMode_Skip, FuncErrorMode_Default:
+	default:
+		err = errors.WithMessagef(ERROR_FUNC_ERROR_MODE, "got:%s", policy.Mode)
+		return normalized, err
+	}
+	if normalized.Retry < 0 {
+		normalized.Retry = 0
+	}
+	return normalized, nil
+}
+
+// validate 执行前校验全部出错策略,避免执行部分函数后才发现配置错误
+func (option CallFuncOption) validate() (err error) {
+	if _, err = option.Policy.normalize(); err != nil {
+		return err
+	}
+	for funcName, policy := range option.Policies {
+		if _, err = policy.normalize(); err != nil {
+			err = errors.WithMessagef(err, "func %s", funcName)
+			return err
+		}
+	}
+	return nil
+}
+
+// policy 函数的出错策略
+func (option CallFuncOption) policy(funcName string) (policy FuncErrorPolicy) {
+	name := strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func)
+	if p, ok := option.Policies[name]; ok {
+		return p
+	}
+	if p, ok := option.Policies[funcName]; ok {
+		return p
+	}
+	return option.Policy
+}
+
+// FuncCallResult 函数执行结果
+type FuncCallResult struct {
+	FuncName string `json:"funcName"`
+	Status   string `json:"status"`           // FuncCallStatus_XXX
+	Attempts int    `json:"attempts"`         // 执行次数,入参缺失时为0
+	Reason   string `json:"reason,omitempty"` // 跳过、使用默认出参、失败的原因
+	Err      error  `json:"-"`
+}
+
+type FuncCallResults []FuncCallResult
+
+// GetByStatus 按状态筛选
+func (rs FuncCallResults) GetByStatus(status string) (subResults FuncCallResults) {
+	subResults = make(FuncCallResults, 0)
+	for _, r := range rs {
+		if r.Status == status {
+			subResults = append(subResults, r)
+		}
+	}
+	return subResults
+}
+
+func (rs FuncCallResults) String() string {
+	var w bytes.Buffer
+	tw := tabwriter.NewWriter(&w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FUNC\tSTATUS\tATTEMPTS\tREASON")
+	for _, r := range rs {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", r.FuncName, r.Status, r.Attempts, r.Reason)
+	}
+	tw.Flush()
+	return w.String()
+}
+
+// CallTransferFuncWithOption 同 CallTransferFunc,按函数的出错策略处理入参缺失、执行出错,results 记录每个函数的执行情况
+// 跳过或使用默认出参的函数不影响后续函数执行(依赖其出参的函数按自身策略处理入参缺失);策略为 fail 时返回错误,results 包含已执行的函数
+func CallTransferFuncWithOption(transfers Transfers, input []byte, option CallFuncOption) (out []byte, results FuncCallResults, err error) {
+	if err = option.validate(); err != nil {
+		return nil, nil, err
+	}
+	funcNames, err := SortTransferFuncs(transfers)
+	if err != nil {
+		return nil, nil, err
+	}
+	closure := option.Closure
+	if closure == nil {
+		closure = DefaultFuncRegistry.Call
+	}
+	results = make(FuncCallResults, 0, len(funcNames))
+	out = input
+	for _, funcName := range funcNames {
+		policy, _ := option.policy(funcName).normalize() // 已校验
+		result := FuncCallResult{FuncName: strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func), Status: FuncCallStatus_Ran}
+		_, outputGopath, err := funcGjsonPaths(transfers, funcName)
+		if err != nil {
+			return nil, results, err
+		}
+		localOut, attempts, callErr := callFuncWithRetry(transfers, funcName, out, closure, policy.Retry)
+		result.Attempts = attempts
+		if callErr != nil {
+			result.Reason, result.Err = callErr.Error(), callErr
+			switch policy.Mode {
+			case FuncErrorMode_Skip:
+				result.Status = FuncCallStatus_Skipped
+				results = append(results, result)
+				continue
+			case FuncErrorMode_Default:
+				result.Status = FuncCallStatus_Default
+				localOut, err = json.Marshal(policy.Defaults)
+				if err != nil {
+					return nil, results, err
+				}
+			default:
+				result.Status = FuncCallStatus_Failed
+				results = append(results, result)
+				return nil, results, callErr
+			}
+		}
+		out, err = mergeFuncOutput(out, localOut, outputGopath)
+		if err != nil {
+			err = errors.WithMessagef(err, "merge func %s output", result.FuncName)
+			return nil, results, err
+		}
+		results = append(results, result)
+	}
+	return out, results, nil
+}
+
+// callFuncWithRetry 执行单个函数,入参缺失时返回全部缺失的入参,执行出错时最多重试 retry 次
+func callFuncWithRetry(transfers Transfers, funcName string, input []byte, closure func(funcname string, input []byte) (out []byte, err error), retry int) (localOut []byte, attempts int, err error) {
+	err = missingFuncArgs(transfers, funcName, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	inputGopath, _, err := funcGjsonPaths(transfers, funcName)
+	if err != nil {
+		return nil, 0, err
+	}
+	noNamespaceFuncName := strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func)
+	localInput := gjson.GetBytes(input, inputGopath).String() // 转换为本地数据格式
+	for attempts < retry+1 {
+		attempts++
+		localOut, err = closure(noNamespaceFuncName, []byte(localInput)) // 执行代码
+		if err == nil {
+			return localOut, attempts, nil
+		}
+		if errors.Is(err, ERROR_TRANSFER_FUNC_NAME_NOT_FOUND) { // 函数不存在,重试无意义
+			break
+		}
+	}
+	inputTransfers := transfers.GetByNamespace(JoinPath(funcName, Transfer_Direction_input).String())
+	err = &FuncCallError{
+		Transfer: strings.TrimSpace(inputTransfers.String()),
+		FuncName: noNamespaceFuncName,
+		Path:     Path(strings.TrimSuffix(funcName, ".")),
+		Input:    localInput,
+		Err:      err,
+	}
+	return nil, attempts, err
+}
@@ -0,0 +1,101 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestCallTransferFuncWithOption(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.vocabulary.Geo.input.ip:request.ip
+func.vocabulary.Geo.output.city:data.city
+func.vocabulary.Weather.input.city:data.city
+func.vocabulary.Weather.output.weather:data.weather
+func.vocabulary.Avatar.input.userId:user.id
+func.vocabulary.Avatar.output.url:data.avatar
+	`)
+	geoCalls := 0
+	closure := func(funcname string, input []byte) (out []byte, err error) {
+		switch funcname {
+		case "vocabulary.Geo":
+			geoCalls++
+			if geoCalls < 3 {
+				return nil, errors.New("timeout")
+			}
+			return []byte(`{"city":"shenzhen"}`), nil
+		case "vocabulary.Weather":
+			return []byte(`{"weather":"sunny"}`), nil
+		}
+		return nil, errors.New("avatar service unavailable")
+	}
+	input := []byte(`{"request":{"ip":"127.0.0.1"},"user":{"id":1}}`)
+
+	t.Run("fail", func(t *testing.T) {
+		geoCalls = 0
+		out, results, err := pathtransfer.CallTransferFuncWithOption(transfers, input, pathtransfer.CallFuncOption{Closure: closure})
+		require.Error(t, err)
+		require.Nil(t, out)
+		require.Len(t, results, 1)
+		require.Equal(t, "vocabulary.Avatar", results[0].FuncName)
+		require.Equal(t, pathtransfer.FuncCallStatus_Failed, results[0].Status)
+		var callErr *pathtransfer.FuncCallError
+		require.ErrorAs(t, err, &callErr)
+	})
+	t.Run("retry skip default", func(t *testing.T) {
+		geoCalls = 0
+		out, results, err := pathtransfer.CallTransferFuncWithOption(transfers, input, pathtransfer.CallFuncOption{
+			Closure: closure,
+			Policy:  pathtransfer.FuncErrorPolicy{Mode: pathtransfer.FuncErrorMode_Skip},
+			Policies: map[string]pathtransfer.FuncErrorPolicy{
+				"vocabulary.Geo":    {Mode: pathtransfer.FuncErrorMode_Fail, Retry: 2},
+				"vocabulary.Avatar": {Mode: pathtransfer.FuncErrorMode_Default, Defaults: map[string]any{"url": "default.png"}},
+			},
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"request":{"ip":"127.0.0.1"},"user":{"id":1},"data":{"avatar":"default.png","city":"shenzhen","weather":"sunny"}}`, string(out))
+		require.Equal(t, []string{"vocabulary.Avatar", "vocabulary.Geo", "vocabulary.Weather"}, []string{results[0].FuncName, results[1].FuncName, results[2].FuncName})
+		require.Equal(t, pathtransfer.FuncCallStatus_Default, results[0].Status)
+		require.Contains(t, results[0].Reason, "avatar service unavailable")
+		require.Equal(t, 3, results[1].Attempts)
+		require.Len(t, results.GetByStatus(pathtransfer.FuncCallStatus_Ran), 2)
+	})
+	t.Run("skip dependents", func(t *testing.T) {
+		geoCalls = 0
+		out, results, err := pathtransfer.CallTransferFuncWithOption(transfers, input, pathtransfer.CallFuncOption{
+			Closure: closure,
+			Policy:  pathtransfer.FuncErrorPolicy{Mode: pathtransfer.FuncErrorMode_Skip},
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, string(input), string(out))
+		skipped := results.GetByStatus(pathtransfer.FuncCallStatus_Skipped)
+		require.Len(t, skipped, 3)
+		require.Equal(t, "vocabulary.Weather", skipped[2].FuncName)
+		require.Equal(t, 0, skipped[2].Attempts)
+		require.ErrorIs(t, skipped[2].Err, pathtransfer.ERROR_TRANSFER_FUNC_ARG_MISSING)
+		require.Contains(t, results.String(), "vocabulary.Weather  skipped")
+	})
+	t.Run("negative retry and mode case", func(t *testing.T) {
+		geoCalls = 2
+		out, results, err := pathtransfer.CallTransferFuncWithOption(transfers, input, pathtransfer.CallFuncOption{
+			Closure: closure,
+			Policy:  pathtransfer.FuncErrorPolicy{Mode: "Skip", Retry: -1},
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"request":{"ip":"127.0.0.1"},"user":{"id":1},"data":{"city":"shenzhen","weather":"sunny"}}`, string(out))
+		skipped := results.GetByStatus(pathtransfer.FuncCallStatus_Skipped)
+		require.Len(t, skipped, 1)
+		require.Equal(t, 1, skipped[0].Attempts)
+		require.Contains(t, skipped[0].Reason, "avatar service unavailable")
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, results, err := pathtransfer.CallTransferFuncWithOption(transfers, input, pathtransfer.CallFuncOption{
+			Closure:  closure,
+			Policies: map[string]pathtransfer.FuncErrorPolicy{"vocabulary.Geo": {Mode: "ignore"}},
+		})
+		require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_ERROR_MODE)
+		require.Empty(t, results)
+	})
+}
@@ -0,0 +1,168 @@
+package pathtransfer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_TRANSFER_FUNC_PLAN_UNREACHABLE = errors.New("no transfer func produce target")
+)
+
+// FuncPlan 函数执行计划,FuncNames 为按依赖排序的函数名,Transfers 为计划涉及的函数转换关系,可直接传给 CallTransferFunc 执行
+type FuncPlan struct {
+	Targets   []Path    `json:"targets"`
+	FuncNames []string  `json:"funcNames"`
+	Transfers Transfers `json:"transfers"`
+}
+
+func (plan FuncPlan) String() (s string) {
+	arr := make([]string, 0, len(plan.FuncNames))
+	for _, funcName := range plan.FuncNames {
+		arr = append(arr, strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func))
+	}
+	return fmt.Sprintf("%s => %s", strings.Join(arr, " -> "), joinPaths(plan.Targets))
+}
+
+func joinPaths(paths []Path) (s string) {
+	arr := make([]string, 0, len(paths))
+	for _, path := range paths {
+		arr = append(arr, path.String())
+	}
+	return strings.Join(arr, ",")
+}
+
+// PlanTransferFuncs 根据已有的key(availableKeys)和期望得到的key(targetKeys),从 transfers 的 func.*.input/output 转换关系中反向查找能产生目标的函数链
+// 目标无法得到时返回的错误为 MultiError,包含无函数产生的目标(ERROR_TRANSFER_FUNC_PLAN_UNREACHABLE)和候选函数缺失的入参(MissingArgError)
+func PlanTransferFuncs(transfers Transfers, availableKeys []Path, targetKeys []Path) (plan FuncPlan, err error) {
+	return planTransferFuncs(transfers, func(path Path) bool {
+		for _, key := range availableKeys {
+			if path == key || strings.HasPrefix(path.String(), key.String()+".") {
+				return true
+			}
+		}
+		return false
+	}, targetKeys)
+}
+
+// PlanTransferFuncsByData 同 PlanTransferFuncs,已有的key为 data 中存在的路径
+func PlanTransferFuncsByData(transfers Transfers, data []byte, targetKeys []Path) (plan FuncPlan, err error) {
+	return planTransferFuncs(transfers, func(path Path) bool {
+		return gjson.GetBytes(data, path.String()).Exists()
+	}, targetKeys)
+}
+
+// funcPlanner 反向链式查找,chosen 为已选中的函数,visiting 避免循环依赖
+type funcPlanner struct {
+	funcTransfers Transfers
+	funcNames     []string
+	inPaths       map[string][]Path
+	outPaths      map[string][]Path
+	available     func(path Path) bool
+	chosen        map[string]bool
+	visiting      map[string]bool
+}
+
+func planTransferFuncs(transfers Transfers, available func(path Path) bool, targetKeys []Path) (plan FuncPlan, err error) {
+	planner := &funcPlanner{
+		funcTransfers: transfers.GetByNamespace(Transfer_Top_Namespace_Func),
+		inPaths:       make(map[string][]Path),
+		outPaths:      make(map[string][]Path),
+		available:     available,
+		chosen:        make(map[string]bool),
+		visiting:      make(map[string]bool),
+	}
+	planner.funcNames = planner.funcTransfers.GetSrcNamespace(Transfer_Direction_output)
+	for _, funcName := range planner.funcNames {
+		in, out := planner.funcTransfers.GetByNamespace(funcName).SplitInOut()
+		planner.inPaths[funcName], planner.outPaths[funcName] = in.GetAllDst(), out.GetAllDst()
+	}
+	plan = FuncPlan{Targets: targetKeys}
+	var errs MultiError
+	for _, target := range targetKeys {
+		errs.Append(planner.resolve(target))
+	}
+	if len(errs) > 0 {
+		return plan, errs
+	}
+	plan.Transfers = make(Transfers, 0)
+	for _, funcName := range planner.funcNames {
+		if planner.chosen[funcName] {
+			plan.Transfers.AddReplace(planner.funcTransfers.GetByNamespace(funcName)...)
+		}
+	}
+	plan.FuncNames, err = SortTransferFuncs(plan.Transfers)
+	if err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+// resolve 目标已存在或可由已选函数产生时直接返回,否则按名称依次尝试产生目标的函数,入参全部可得时选中该函数;均不可得时返回第一个候选函数的原因
+func (planner *funcPlanner) resolve(target Path) (err error) {
+	if planner.available(target) {
+		return nil
+	}
+	producers := make([]string, 0)
+	for _, funcName := range planner.funcNames {
+		if pathsOverlap(planner.outPaths[funcName], []Path{target}) {
+			if planner.chosen[funcName] {
+				return nil
+			}
+			producers = append(producers, funcName)
+		}
+	}
+	if len(producers) == 0 {
+		return errors.WithMessagef(ERROR_TRANSFER_FUNC_PLAN_UNREACHABLE, "target:%s", target)
+	}
+	sort.Strings(producers)
+	var firstErr error
+	for _, funcName := range producers {
+		if planner.visiting[funcName] {
+			continue
+		}
+		err = planner.resolveFunc(funcName)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil { // 候选函数都在依赖链中,说明存在循环依赖
+		firstErr = errors.WithMessagef(ERROR_TRANSFER_FUNC_CYCLE, "target:%s funcs:%s", target, strings.Join(producers, ","))
+	}
+	return firstErr
+}
+
+// resolveFunc 函数入参全部可得时选中函数及其依赖的函数
+func (planner *funcPlanner) resolveFunc(funcName string) (err error) {
+	planner.visiting[funcName] = true
+	defer delete(planner.visiting, funcName)
+	chosen := make(map[string]bool)
+	for name := range planner.chosen {
+		chosen[name] = true
+	}
+	var errs MultiError
+	inputTransfers := planner.funcTransfers.GetByNamespace(JoinPath(funcName, Transfer_Direction_input).String())
+	for _, t := range inputTransfers {
+		err = planner.resolve(t.Dst.Path)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ERROR_TRANSFER_FUNC_PLAN_UNREACHABLE) { // 没有函数产生的入参即为缺失的入参
+			err = &MissingArgError{Transfer: t.String(), FuncName: strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func), Path: t.Dst.Path}
+		}
+		errs.Append(err)
+	}
+	if len(errs) > 0 {
+		planner.chosen = chosen // 回退本函数依赖链中选中的函数
+		return errs
+	}
+	planner.chosen[funcName] = true
+	return nil
+}
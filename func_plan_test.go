@@ -0,0 +1,59 @@
+package pathtransfer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestPlanTransferFuncs(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+user.name@string:data.userName
+func.vocabulary.TrimName.input.name@string:user.name
+func.vocabulary.TrimName.output.name@string:data.userName
+func.vocabulary.Greet.input.name@string:data.userName
+func.vocabulary.Greet.output.greeting@string:data.greeting
+func.vocabulary.SetLimit.input.index@int:data.pagination.index
+func.vocabulary.SetLimit.input.size@int:data.pagination.size
+func.vocabulary.SetLimit.output.offset@int:data.limit.offset
+func.vocabulary.SetLimit.output.size@int:data.limit.size
+	`)
+	data := []byte(`{"data":{"pagination":{"index":1,"size":20}},"user":{"name":" testName "}}`)
+
+	t.Run("single", func(t *testing.T) {
+		plan, err := pathtransfer.PlanTransferFuncsByData(transfers, data, []pathtransfer.Path{"data.limit.offset", "data.limit.size"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"func.vocabulary.SetLimit"}, plan.FuncNames)
+		require.Equal(t, 4, len(plan.Transfers))
+	})
+	t.Run("chain", func(t *testing.T) {
+		plan, err := pathtransfer.PlanTransferFuncs(transfers, []pathtransfer.Path{"user"}, []pathtransfer.Path{"data.greeting", "data.limit"})
+		require.Error(t, err)
+		plan, err = pathtransfer.PlanTransferFuncs(transfers, []pathtransfer.Path{"user", "data.pagination"}, []pathtransfer.Path{"data.greeting", "data.limit"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"func.vocabulary.SetLimit", "func.vocabulary.TrimName", "func.vocabulary.Greet"}, plan.FuncNames)
+		require.Equal(t, "vocabulary.SetLimit -> vocabulary.TrimName -> vocabulary.Greet => data.greeting,data.limit", plan.String())
+		out, err := pathtransfer.CallTransferFunc(plan.Transfers, data, func(funcname string, input []byte) (out []byte, err error) {
+			switch funcname {
+			case "vocabulary.TrimName":
+				return []byte(`{"name":"testName"}`), nil
+			case "vocabulary.Greet":
+				return []byte(`{"greeting":"hello"}`), nil
+			}
+			return []byte(`{"offset":0,"size":20}`), nil
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"data":{"pagination":{"index":1,"size":20},"limit":{"offset":0,"size":20},"userName":"testName","greeting":"hello"},"user":{"name":" testName "}}`, string(out))
+	})
+	t.Run("missing", func(t *testing.T) {
+		_, err := pathtransfer.PlanTransferFuncsByData(transfers, []byte(`{"data":{"pagination":{"size":20}}}`), []pathtransfer.Path{"data.limit.offset", "data.total"})
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_PLAN_UNREACHABLE)
+		var missing *pathtransfer.MissingArgError
+		require.True(t, errors.As(err, &missing))
+		require.Equal(t, "vocabulary.SetLimit", missing.FuncName)
+		require.Equal(t, pathtransfer.Path("data.pagination.index"), missing.Path)
+		require.Len(t, err.(pathtransfer.MultiError), 2)
+	})
+}
@@ -0,0 +1,373 @@
+package pathtransfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/suifengpiao14/pathtransfer/transferfunc"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_FUNC_REGISTRY_INVALID_FUNC = errors.New("invalid registry func")
+	ERROR_FUNC_REGISTRY_ARG_TYPE     = errors.New("func arg type mismatch")
+)
+
+// RegisteredFunc 注册的go函数,Input、Output 和转换协议中的 FuncParameter 对应
+type RegisteredFunc struct {
+	Package  string         `json:"package"`
+	FuncName string         `json:"funcName"`
+	Input    FuncParameters `json:"input"`
+	Output   FuncParameters `json:"output"`
+	fn       reflect.Value
+	// 入参、出参为结构体时,所有参数通过一个结构体传递
+	inputStruct  bool
+	outputStruct bool
+}
+
+// Name 注册名称 package.FuncName
+func (rf RegisteredFunc) Name() string {
+	return strings.TrimPrefix(fmt.Sprintf("%s.%s", rf.Package, rf.FuncName), ".")
+}
+
+// FuncRegistry go函数注册表,按 package.FuncName 注册,Call 可以直接作为 CallTransferFunc 的 closure
+type FuncRegistry struct {
+	mu    sync.RWMutex
+	funcs map[string]*RegisteredFunc
+}
+
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{
+		funcs: make(map[string]*RegisteredFunc),
+	}
+}
+
+// DefaultFuncRegistry 默认注册表,已注册 transferfunc 包中的函数,CallTransferFunc 的 closure 为nil时使用
+var DefaultFuncRegistry = NewFuncRegistry()
+
+func init() {
+	DefaultFuncRegistry.MustRegister("transferfunc.Limit", transferfunc.Limit, []string{"index", "size"}, []string{"offset", "limit"})
+	DefaultFuncRegistry.MustRegister("transferfunc.LikePrefix", transferfunc.LikePrefix, []string{"value"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.LikeSuffix", transferfunc.LikeSuffix, []string{"value"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Like", transferfunc.Like, []string{"value"}, []string{"newValue"})
+	// 字符串
+	DefaultFuncRegistry.MustRegister("transferfunc.Trim", transferfunc.Trim, []string{"value"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Upper", transferfunc.Upper, []string{"value"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Lower", transferfunc.Lower, []string{"value"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Substr", transferfunc.Substr, []string{"value", "start", "length"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Split", transferfunc.Split, []string{"value", "sep"}, []string{"items"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Join", transferfunc.Join, []string{"items", "sep"}, []string{"value"})
+	DefaultFuncRegistry.MustRegister("transferfunc.RegexReplace", transferfunc.RegexReplace, []string{"value", "pattern", "replacement"}, []string{"newValue"})
+	// 分页
+	DefaultFuncRegistry.MustRegister("transferfunc.PageOffset", transferfunc.PageOffset, []string{"page", "size"}, []string{"offset", "limit"})
+	DefaultFuncRegistry.MustRegister("transferfunc.OffsetPage", transferfunc.OffsetPage, []string{"offset", "limit"}, []string{"page", "size"})
+	DefaultFuncRegistry.MustRegister("transferfunc.TotalPages", transferfunc.TotalPages, []string{"total", "size"}, []string{"pages"})
+	DefaultFuncRegistry.MustRegister("transferfunc.CursorEncode", transferfunc.CursorEncode, []string{"lastID"}, []string{"cursor"})
+	DefaultFuncRegistry.MustRegister("transferfunc.CursorDecode", transferfunc.CursorDecode, []string{"cursor"}, []string{"lastID"})
+	// 时间
+	DefaultFuncRegistry.MustRegister("transferfunc.TimeFormat", transferfunc.TimeFormat, []string{"value", "fromLayout", "toLayout"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.UnixToTime", transferfunc.UnixToTime, []string{"timestamp", "layout"}, []string{"value"})
+	DefaultFuncRegistry.MustRegister("transferfunc.TimeToUnix", transferfunc.TimeToUnix, []string{"value", "layout"}, []string{"timestamp"})
+	// 摘要、脱敏
+	DefaultFuncRegistry.MustRegister("transferfunc.Md5", transferfunc.Md5, []string{"value"}, []string{"hash"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Sha1", transferfunc.Sha1, []string{"value"}, []string{"hash"})
+	DefaultFuncRegistry.MustRegister("transferfunc.MaskPhone", transferfunc.MaskPhone, []string{"phone"}, []string{"masked"})
+	DefaultFuncRegistry.MustRegister("transferfunc.MaskEmail", transferfunc.MaskEmail, []string{"email"}, []string{"masked"})
+	// 数值
+	DefaultFuncRegistry.MustRegister("transferfunc.Round", transferfunc.Round, []string{"value", "precision"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Ceil", transferfunc.Ceil, []string{"value", "precision"}, []string{"newValue"})
+	DefaultFuncRegistry.MustRegister("transferfunc.Floor", transferfunc.Floor, []string{"value", "precision"}, []string{"newValue"})
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Register 注册函数,name 格式为 [package.]FuncName
+// inputNames、outputNames 为参数名称(go 反射无法获取参数名),为空时:参数是结构体则使用结构体字段(json tag)作为参数,否则使用 arg0、out0 ...
+// 最后一个返回值为 error 时作为函数错误,不计入出参
+func (r *FuncRegistry) Register(name string, fn any, inputNames []string, outputNames []string) (err error) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		err = errors.WithMessagef(ERROR_FUNC_REGISTRY_INVALID_FUNC, "%s require func,got:%T", name, fn)
+		return err
+	}
+	if rv.Type().IsVariadic() { // 按参数名逐个传参,不支持可变参数
+		err = errors.WithMessagef(ERROR_FUNC_REGISTRY_INVALID_FUNC, "%s variadic func not supported", name)
+		return err
+	}
+	rf := &RegisteredFunc{fn: rv}
+	if lastDot := strings.LastIndex(name, "."); lastDot > -1 {
+		rf.Package, rf.FuncName = name[:lastDot], name[lastDot+1:]
+	} else {
+		rf.FuncName = name
+	}
+	rt := rv.Type()
+	inTypes := make([]reflect.Type, 0)
+	for i := 0; i < rt.NumIn(); i++ {
+		inTypes = append(inTypes, rt.In(i))
+	}
+	outTypes := make([]reflect.Type, 0)
+	for i := 0; i < rt.NumOut(); i++ {
+		if i == rt.NumOut()-1 && rt.Out(i) == errorType {
+			continue
+		}
+		outTypes = append(outTypes, rt.Out(i))
+	}
+	rf.Input, rf.inputStruct, err = rf.funcParameters(Transfer_Direction_input, inTypes, inputNames, "arg")
+	if err != nil {
+		return err
+	}
+	rf.Output, rf.outputStruct, err = rf.funcParameters(Transfer_Direction_output, outTypes, outputNames, "out")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[rf.Name()] = rf
+	return nil
+}
+
+// MustRegister 同 Register,出错时panic,用于init中注册
+func (r *FuncRegistry) MustRegister(name string, fn any, inputNames []string, outputNames []string) {
+	err := r.Register(name, fn, inputNames, outputNames)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// funcParameters 生成参数定义,单个结构体参数且未指定名称时展开结构体字段
+func (rf RegisteredFunc) funcParameters(direction string, types []reflect.Type, names []string, defaultPrefix string) (fps FuncParameters, isStruct bool, err error) {
+	fps = make(FuncParameters, 0)
+	if len(names) == 0 && len(types) == 1 && types[0].Kind() == reflect.Struct {
+		isStruct = true
+		for _, field := range reflect.VisibleFields(types[0]) {
+			fieldName, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			fps = append(fps, rf.funcParameter(direction, fieldName, field.Type))
+		}
+		return fps, isStruct, nil
+	}
+	if len(names) > 0 && len(names) != len(types) {
+		err = errors.WithMessagef(ERROR_FUNC_REGISTRY_INVALID_FUNC, "%s %s names count %d not match %d", rf.Name(), strings.Trim(direction, "."), len(names), len(types))
+		return nil, false, err
+	}
+	for i, typ := range types {
+		name := fmt.Sprintf("%s%d", defaultPrefix, i)
+		if len(names) > 0 {
+			name = names[i]
+		}
+		fps = append(fps, rf.funcParameter(direction, name, typ))
+	}
+	return fps, false, nil
+}
+
+func (rf RegisteredFunc) funcParameter(direction string, name string, typ reflect.Type) (fp FuncParameter) {
+	fp = reflectFuncField(JoinPath(Transfer_Top_Namespace_Func, rf.Name(), direction), name, typ)
+	fp.Direction, fp.Package, fp.FuncName = direction, rf.Package, rf.FuncName
+	return fp
+}
+
+// reflectFuncField go类型生成参数(字段)定义,结构体、结构体切片递归生成 Fields
+func reflectFuncField(parent Path, name string, typ reflect.Type) (field FuncParameter) {
+	field = FuncParameter{Name: name, Path: JoinPath(parent.String(), name), Type: reflectTypeName(typ)}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	fieldsParent := field.Path
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		fieldsParent = JoinPath(field.Path.String(), "#")
+		if typ.Kind() != reflect.Struct {
+			field.ItemType = reflectTypeName(typ)
+			return field
+		}
+	}
+	if typ.Kind() != reflect.Struct {
+		return field
+	}
+	for _, structField := range reflect.VisibleFields(typ) {
+		fieldName, ok := jsonFieldName(structField)
+		if !ok {
+			continue
+		}
+		field.Fields = append(field.Fields, reflectFuncField(fieldsParent, fieldName, structField.Type))
+	}
+	return field
+}
+
+// jsonFieldName 结构体字段对应的json key,和 encoding/json 保持一致
+func jsonFieldName(field reflect.StructField) (name string, ok bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// reflectTypeName go类型对应转换协议中的类型
+func reflectTypeName(typ reflect.Type) (name string) {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TransferUnit_Type_Int
+	case reflect.Float32, reflect.Float64:
+		return TransferUnit_Type_Number
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return TransferUnit_Type_String
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Ptr:
+		return reflectTypeName(typ.Elem())
+	}
+	return "object"
+}
+
+// Get 获取注册的函数
+func (r *FuncRegistry) Get(name string) (rf *RegisteredFunc, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rf, ok = r.funcs[name]
+	return rf, ok
+}
+
+// Names 所有注册的函数名称,按名称排序
+func (r *FuncRegistry) Names() (names []string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names = make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FuncParameters 所有注册函数的参数
+func (r *FuncRegistry) FuncParameters() (fps FuncParameters) {
+	fps = make(FuncParameters, 0)
+	for _, name := range r.Names() {
+		rf, _ := r.Get(name)
+		fps = append(fps, rf.Input...)
+		fps = append(fps, rf.Output...)
+	}
+	return fps
+}
+
+// Call 执行函数,input 为入参json对象(key为参数名),返回出参json对象,签名和 CallTransferFunc 的 closure 一致
+func (r *FuncRegistry) Call(funcName string, input []byte) (out []byte, err error) {
+	rf, ok := r.Get(funcName)
+	if !ok {
+		err = errors.WithMessagef(ERROR_TRANSFER_FUNC_NAME_NOT_FOUND, "func:%s", funcName)
+		return nil, err
+	}
+	return rf.Call(input)
+}
+
+// Call 按参数定义将json入参转换为go类型调用函数,出参转换为json对象
+func (rf RegisteredFunc) Call(input []byte) (out []byte, err error) {
+	rt := rf.fn.Type()
+	args := make([]reflect.Value, 0, rt.NumIn())
+	if rf.inputStruct {
+		arg := reflect.New(rt.In(0))
+		if len(input) > 0 {
+			if err = json.Unmarshal(input, arg.Interface()); err != nil {
+				err = errors.WithMessagef(ERROR_FUNC_REGISTRY_ARG_TYPE, "%s input:%s", rf.Name(), err.Error())
+				return nil, err
+			}
+		}
+		args = append(args, arg.Elem())
+	} else {
+		for i, fp := range rf.Input {
+			arg, err := jsonToReflectValue(gjson.GetBytes(input, gjson.Escape(fp.Name)), rt.In(i))
+			if err != nil {
+				err = errors.WithMessagef(err, "%s arg %s", rf.Name(), fp.Name)
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+	}
+	results := rf.fn.Call(args)
+	if rt.NumOut() > 0 && rt.Out(rt.NumOut()-1) == errorType {
+		if errValue := results[len(results)-1]; !errValue.IsNil() {
+			return nil, errValue.Interface().(error)
+		}
+		results = results[:len(results)-1]
+	}
+	if rf.outputStruct {
+		return json.Marshal(results[0].Interface())
+	}
+	outMap := make(map[string]any)
+	for i, fp := range rf.Output {
+		outMap[fp.Name] = results[i].Interface()
+	}
+	return json.Marshal(outMap)
+}
+
+// jsonToReflectValue json值转换为指定的go类型,基础类型使用 cast 宽松转换(如 "1" 转 int),不存在时为零值
+func jsonToReflectValue(result gjson.Result, typ reflect.Type) (value reflect.Value, err error) {
+	value = reflect.New(typ).Elem()
+	if !result.Exists() || result.Type == gjson.Null {
+		return value, nil
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if result.Type == gjson.Number && result.Num != math.Trunc(result.Num) { // 小数传给整数参数会被截断
+			err = errors.WithMessagef(ERROR_FUNC_REGISTRY_ARG_TYPE, "value %s to %s", result.Raw, typ.String())
+			return value, err
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = cast.ToInt64E(result.Value())
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		u, err = cast.ToUint64E(result.Value())
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = cast.ToFloat64E(result.Value())
+		value.SetFloat(f)
+	case reflect.Bool:
+		var b bool
+		b, err = cast.ToBoolE(result.Value())
+		value.SetBool(b)
+	case reflect.String:
+		s := result.String()
+		if result.IsObject() || result.IsArray() {
+			s = result.Raw
+		}
+		value.SetString(s)
+	default:
+		ptr := reflect.New(typ)
+		err = json.Unmarshal([]byte(result.Raw), ptr.Interface())
+		value = ptr.Elem()
+	}
+	if err != nil {
+		err = errors.WithMessagef(ERROR_FUNC_REGISTRY_ARG_TYPE, "value %s to %s", result.Raw, typ.String())
+		return value, err
+	}
+	return value, nil
+}
@@ -0,0 +1,127 @@
+package pathtransfer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+type trimNameIn struct {
+	Name   string `json:"name"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+type trimNameOut struct {
+	Name string `json:"name"`
+}
+
+func TestFuncRegistry(t *testing.T) {
+	registry := pathtransfer.NewFuncRegistry()
+	err := registry.Register("vocabulary.TrimName", func(in trimNameIn) (out trimNameOut, err error) {
+		if in.Name == "" {
+			return out, errors.New("name required")
+		}
+		out.Name = strings.TrimPrefix(strings.TrimSpace(in.Name), in.Prefix)
+		return out, nil
+	}, nil, nil)
+	require.NoError(t, err)
+	err = registry.Register("vocabulary.SetLimit", func(index int, size int) (offset int, limit int) {
+		return index * size, size
+	}, []string{"index", "size"}, []string{"offset", "size"})
+	require.NoError(t, err)
+
+	rf, ok := registry.Get("vocabulary.SetLimit")
+	require.True(t, ok)
+	require.Equal(t, pathtransfer.FuncParameter{
+		Direction: ".input",
+		Package:   "vocabulary",
+		FuncName:  "SetLimit",
+		Name:      "index",
+		Path:      "func.vocabulary.SetLimit.input.index",
+		Type:      "int",
+	}, rf.Input[0])
+	rf, _ = registry.Get("vocabulary.TrimName")
+	require.Equal(t, "name,prefix", rf.Input.Names())
+	require.Equal(t, []string{"vocabulary.SetLimit", "vocabulary.TrimName"}, registry.Names())
+
+	out, err := registry.Call("vocabulary.SetLimit", []byte(`{"index":"2","size":20}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"offset":40,"size":20}`, string(out))
+
+	out, err = registry.Call("vocabulary.TrimName", []byte(`{"name":" Mr.Zhang ","prefix":"Mr."}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"Zhang"}`, string(out))
+
+	_, err = registry.Call("vocabulary.TrimName", []byte(`{}`))
+	require.EqualError(t, err, "name required")
+	_, err = registry.Call("vocabulary.SetLimit", []byte(`{"index":"a"}`))
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_REGISTRY_ARG_TYPE)
+	_, err = registry.Call("vocabulary.SetLimit", []byte(`{"index":2.5,"size":20}`))
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_REGISTRY_ARG_TYPE)
+	out, err = registry.Call("vocabulary.SetLimit", []byte(`{"index":2.0,"size":20}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"offset":40,"size":20}`, string(out))
+	_, err = registry.Call("vocabulary.Missing", nil)
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_NAME_NOT_FOUND)
+	err = registry.Register("bad", "not func", nil, nil)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_REGISTRY_INVALID_FUNC)
+	err = registry.Register("bad", func(a, b int) {}, []string{"a"}, nil)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_REGISTRY_INVALID_FUNC)
+	err = registry.Register("bad", func(sep string, values ...string) {}, []string{"sep", "values"}, nil)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_REGISTRY_INVALID_FUNC)
+
+	transfers := pathtransfer.Parse(`
+func.vocabulary.SetLimit.input.index@int:data.pagination.index
+func.vocabulary.SetLimit.input.size@int:data.pagination.size
+func.vocabulary.SetLimit.output.offset@int:data.limit.offset
+func.vocabulary.SetLimit.output.size@int:data.limit.size
+	`)
+	out, err = pathtransfer.CallTransferFunc(transfers, []byte(`{"data":{"pagination":{"index":1,"size":20}}}`), registry.Call)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), gjson.GetBytes(out, "data.limit.offset").Int())
+}
+
+func TestCallTransferFuncDefaultRegistry(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.transferfunc.Limit.input.index@int:data.pagination.index
+func.transferfunc.Limit.input.size@int:data.pagination.size
+func.transferfunc.Limit.output.offset@int:data.limit.offset
+func.transferfunc.Limit.output.limit@int:data.limit.size
+	`)
+	out, err := pathtransfer.CallTransferFunc(transfers, []byte(`{"data":{"pagination":{"index":2,"size":10}}}`), nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":{"pagination":{"index":2,"size":10},"limit":{"offset":20,"size":10}}}`, string(out))
+}
+
+func TestDefaultFuncRegistrySignatures(t *testing.T) {
+	signatures, err := pathtransfer.ParseGoFuncSignaturesDir("transferfunc")
+	require.NoError(t, err)
+	for _, registered := range pathtransfer.DefaultFuncRegistry.Signatures() {
+		declared, ok := signatures.Get(registered.Name())
+		require.True(t, ok, registered.Name())
+		require.Equal(t, declared.Input.Names(), registered.Input.Names(), registered.Name())
+		require.Equal(t, declared.Output.Names(), registered.Output.Names(), registered.Name())
+		for i := range declared.Input {
+			require.Equal(t, declared.Input[i].Type, registered.Input[i].Type, registered.Name())
+		}
+	}
+
+	transfers := pathtransfer.Parse(`
+func.transferfunc.Trim.input.value:user.name
+func.transferfunc.Trim.output.newValue:data.userName
+func.transferfunc.MaskPhone.input.phone:user.phone
+func.transferfunc.MaskPhone.output.masked:data.phone
+func.transferfunc.PageOffset.input.page@int:query.page
+func.transferfunc.PageOffset.input.size@int:query.size
+func.transferfunc.PageOffset.output.offset@int:data.limit.offset
+func.transferfunc.PageOffset.output.limit@int:data.limit.size
+	`)
+	require.NoError(t, pathtransfer.ValidateFuncTransfers(transfers, pathtransfer.DefaultFuncRegistry.Signatures()))
+	out, err := pathtransfer.CallTransferFunc(transfers, []byte(`{"user":{"name":" zhang ","phone":"13812345678"},"query":{"page":"2","size":10}}`), nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"userName":"zhang","phone":"138****5678","limit":{"offset":10,"size":10}}`, gjson.GetBytes(out, "data").Raw)
+}
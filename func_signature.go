@@ -0,0 +1,387 @@
+package pathtransfer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ERROR_FUNC_SIGNATURE_UNKNOWN_FUNC  = errors.New("unknown func")
+	ERROR_FUNC_SIGNATURE_MISSING_ARG   = errors.New("missing func arg")
+	ERROR_FUNC_SIGNATURE_EXTRA_ARG     = errors.New("extra func arg")
+	ERROR_FUNC_SIGNATURE_TYPE_MISMATCH = errors.New("func arg type mismatch")
+)
+
+// FuncSignatureError 转换关系和函数签名不一致,Err 为具体类型(ERROR_FUNC_SIGNATURE_XXX)
+type FuncSignatureError struct {
+	FuncName string `json:"funcName"`
+	Path     Path   `json:"path,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Err      error  `json:"-"`
+}
+
+func (e *FuncSignatureError) Error() string {
+	s := fmt.Sprintf("%s: func %s", e.Err, e.FuncName)
+	if e.Path != "" {
+		s = fmt.Sprintf("%s arg %s", s, e.Path)
+	}
+	if e.Expected != "" || e.Actual != "" {
+		s = fmt.Sprintf("%s expected %s got %s", s, e.Expected, e.Actual)
+	}
+	return s
+}
+
+func (e *FuncSignatureError) Unwrap() error {
+	return e.Err
+}
+
+// Signatures 注册函数的签名,用于 ValidateFuncTransfers
+func (r *FuncRegistry) Signatures() (signatures CallFuncs) {
+	signatures = make(CallFuncs, 0)
+	for _, name := range r.Names() {
+		rf, _ := r.Get(name)
+		signatures = append(signatures, CallFunc{Package: rf.Package, FuncName: rf.FuncName, Input: rf.Input, Output: rf.Output})
+	}
+	return signatures
+}
+
+// Get 按名称(package.FuncName)获取函数签名
+func (cfs CallFuncs) Get(name string) (cf *CallFunc, ok bool) {
+	name = strings.TrimPrefix(name, Transfer_Top_Namespace_Func)
+	for i := range cfs {
+		if cfs[i].Name() == name {
+			return &cfs[i], true
+		}
+	}
+	return nil, false
+}
+
+// ValidateFuncTransfers 校验 transfers 中 func.*.input/output 转换关系和函数签名是否一致
+// 返回 MultiError,每一项为 *FuncSignatureError:未声明的函数、缺失的入参、多余的参数、类型不一致
+func ValidateFuncTransfers(transfers Transfers, signatures CallFuncs) (err error) {
+	callFuncs, err := transfers.CallFuncs()
+	if err != nil {
+		return err
+	}
+	var errs MultiError
+	for _, cf := range callFuncs {
+		signature, ok := signatures.Get(cf.Name())
+		if !ok {
+			errs.Append(&FuncSignatureError{FuncName: cf.Name(), Err: ERROR_FUNC_SIGNATURE_UNKNOWN_FUNC})
+			continue
+		}
+		errs.Append(validateFuncParameters(cf.Name(), signature.Input, cf.Input, true))
+		errs.Append(validateFuncParameters(cf.Name(), signature.Output, cf.Output, false))
+	}
+	return errs.ErrorOrNil()
+}
+
+// validateFuncParameters 比较声明的参数和转换关系中的参数,requireAll 为 true 时声明的参数都必须存在(入参)
+func validateFuncParameters(funcName string, declared FuncParameters, actual FuncParameters, requireAll bool) (err error) {
+	var errs MultiError
+	declaredByName := make(map[string]FuncParameter)
+	for _, fp := range declared {
+		declaredByName[fp.Name] = fp
+	}
+	actualNames := make(map[string]bool)
+	for _, fp := range actual {
+		actualNames[fp.Name] = true
+		expected, ok := declaredByName[fp.Name]
+		if !ok {
+			errs.Append(&FuncSignatureError{FuncName: funcName, Path: fp.Path, Err: ERROR_FUNC_SIGNATURE_EXTRA_ARG})
+			continue
+		}
+		if !funcTypeCompatible(expected, fp) {
+			errs.Append(&FuncSignatureError{FuncName: funcName, Path: fp.Path, Expected: expected.typeString(), Actual: fp.typeString(), Err: ERROR_FUNC_SIGNATURE_TYPE_MISMATCH})
+			continue
+		}
+		if len(expected.Fields) > 0 && len(fp.Fields) > 0 { // 对象字段只校验多余字段和类型,缺失的字段为零值
+			errs.Append(validateFuncParameters(funcName, expected.Fields, fp.Fields, false))
+		}
+	}
+	if requireAll {
+		for _, fp := range declared {
+			if !actualNames[fp.Name] {
+				errs.Append(&FuncSignatureError{FuncName: funcName, Path: fp.Path, Err: ERROR_FUNC_SIGNATURE_MISSING_ARG})
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// normalizeFuncType 统一类型名称,空表示任意类型
+func normalizeFuncType(typ string) (normalized string) {
+	typ = strings.ToLower(typ)
+	switch typ {
+	case "int64", "integer":
+		return TransferUnit_Type_Int
+	case "float", "float64", "double":
+		return TransferUnit_Type_Number
+	case "boolean":
+		return "bool"
+	case "any", "interface{}":
+		return ""
+	}
+	return typ
+}
+
+// funcTypeCompatible 转换关系未指定类型时兼容任意类型,数值类型参数兼容整型
+func funcTypeCompatible(expected FuncParameter, actual FuncParameter) bool {
+	e, a := normalizeFuncType(expected.Type), normalizeFuncType(actual.Type)
+	if e == "" || a == "" || e == a {
+		if e == "array" && a == "array" && expected.ItemType != "" && actual.ItemType != "" {
+			return funcTypeCompatible(FuncParameter{Type: expected.ItemType}, FuncParameter{Type: actual.ItemType})
+		}
+		return true
+	}
+	return e == TransferUnit_Type_Number && a == TransferUnit_Type_Int
+}
+
+func (fp FuncParameter) typeString() (s string) {
+	if fp.ItemType != "" {
+		return fmt.Sprintf("%s<%s>", fp.Type, fp.ItemType)
+	}
+	return fp.Type
+}
+
+// ParseGoFuncSignatures 从go源码中提取导出函数(不含方法)的签名,包名取源码的 package
+// 参数名、出参名取源码中的名称,未命名时为 arg0、out0 ...;最后一个出参为 error 时不计入出参;同文件中声明的结构体展开为 Fields
+func ParseGoFuncSignatures(filename string, src any) (signatures CallFuncs, err error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	return goFileSignatures([]*ast.File{file}), nil
+}
+
+// ParseGoFuncSignaturesDir 从目录(一个go包)中提取导出函数的签名,忽略 _test.go 文件
+func ParseGoFuncSignaturesDir(dir string) (signatures CallFuncs, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return goFileSignatures(files), nil
+}
+
+// goFileSignatures 提取签名,按函数名排序
+func goFileSignatures(files []*ast.File) (signatures CallFuncs) {
+	types := make(map[string]ast.Expr) // 包内定义的类型,如结构体、type ID int64
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				types[typeSpec.Name.Name] = typeSpec.Type
+			}
+		}
+	}
+	signatures = make(CallFuncs, 0)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Recv != nil || !funcDecl.Name.IsExported() {
+				continue
+			}
+			cf := CallFunc{Package: file.Name.Name, FuncName: funcDecl.Name.Name}
+			sp := goSignatureParser{types: types}
+			cf.Input = sp.fieldList(cf, Transfer_Direction_input, funcDecl.Type.Params, "arg")
+			results := funcDecl.Type.Results
+			if results != nil && len(results.List) > 0 {
+				last := results.List[len(results.List)-1]
+				if ident, ok := last.Type.(*ast.Ident); ok && ident.Name == "error" {
+					results = &ast.FieldList{List: append([]*ast.Field{}, results.List[:len(results.List)-1]...)}
+					if len(last.Names) > 1 { // (a, err error) 形式,保留前面的出参
+						results.List = append(results.List, &ast.Field{Names: last.Names[:len(last.Names)-1], Type: last.Type})
+					}
+				}
+			}
+			cf.Output = sp.fieldList(cf, Transfer_Direction_output, results, "out")
+			signatures = append(signatures, cf)
+		}
+	}
+	sort.SliceStable(signatures, func(i, j int) bool { return signatures[i].FuncName < signatures[j].FuncName })
+	return signatures
+}
+
+// goSignatureParser go类型表达式转换为参数定义,visiting 避免类型循环引用
+type goSignatureParser struct {
+	types    map[string]ast.Expr
+	visiting map[string]bool
+}
+
+// named 包内定义的类型,返回类型定义和标记了该类型的解析器;未定义或循环引用时 ok 为 false
+func (p goSignatureParser) named(name string) (typeExpr ast.Expr, sub goSignatureParser, ok bool) {
+	typeExpr, ok = p.types[name]
+	if !ok || p.visiting[name] {
+		return nil, p, false
+	}
+	visiting := map[string]bool{name: true}
+	for k := range p.visiting {
+		visiting[k] = true
+	}
+	return typeExpr, goSignatureParser{types: p.types, visiting: visiting}, true
+}
+
+func (p goSignatureParser) fieldList(cf CallFunc, direction string, fields *ast.FieldList, defaultPrefix string) (fps FuncParameters) {
+	fps = make(FuncParameters, 0)
+	if fields == nil {
+		return fps
+	}
+	parent := JoinPath(Transfer_Top_Namespace_Func, cf.Name(), direction)
+	for _, field := range fields.List {
+		names := make([]string, 0)
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, fmt.Sprintf("%s%d", defaultPrefix, len(fps)))
+		}
+		for _, name := range names {
+			fp := p.field(parent, name, field.Type)
+			fp.Direction, fp.Package, fp.FuncName = direction, cf.Package, cf.FuncName
+			fps = append(fps, fp)
+		}
+	}
+	return fps
+}
+
+func (p goSignatureParser) field(parent Path, name string, expr ast.Expr) (fp FuncParameter) {
+	fp = FuncParameter{Name: name, Path: JoinPath(parent.String(), name)}
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		fp = p.field(parent, name, t.X)
+	case *ast.ArrayType:
+		fp.Type = "array"
+		item := p.field(JoinPath(fp.Path.String(), "#"), "", t.Elt)
+		if item.Type == "object" && len(item.Fields) > 0 {
+			fp.Fields = item.Fields
+		} else {
+			fp.ItemType = item.Type
+		}
+	case *ast.MapType:
+		fp.Type = "object"
+	case *ast.InterfaceType:
+		fp.Type = ""
+	case *ast.StructType:
+		fp.Type = "object"
+		fp.Fields = p.structFields(fp.Path, t)
+	case *ast.Ident:
+		fp.Type = goIdentType(t.Name)
+		if typeExpr, sub, ok := p.named(t.Name); ok { // 包内定义的类型取底层类型,如 type ID int64 为 int
+			fp = sub.field(parent, name, typeExpr)
+		}
+	default: // 其它包的类型,如 time.Time
+		fp.Type = "object"
+	}
+	return fp
+}
+
+// structFields 结构体字段,名称取 json tag,和 encoding/json 保持一致;嵌入结构体的字段提升到当前层级,同 reflect.VisibleFields
+func (p goSignatureParser) structFields(parent Path, structType *ast.StructType) (fps FuncParameters) {
+	fps, _ = p.visibleFields(parent, structType)
+	return fps
+}
+
+// visibleFields 结构体字段及其go字段名,嵌入结构体的字段被外层同名(go字段名)字段覆盖时忽略
+func (p goSignatureParser) visibleFields(parent Path, structType *ast.StructType) (fps FuncParameters, goNames []string) {
+	fps = make(FuncParameters, 0)
+	outer := make(map[string]bool)
+	for _, field := range structType.Fields.List {
+		for _, name := range field.Names {
+			outer[name.Name] = true
+		}
+	}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			promotedFps, promotedNames := p.embeddedFields(parent, field.Type)
+			for i, fp := range promotedFps {
+				if !outer[promotedNames[i]] {
+					fps, goNames = append(fps, fp), append(goNames, promotedNames[i])
+				}
+			}
+			continue
+		}
+		tag := ""
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fieldName, ok := jsonFieldName(reflect.StructField{Name: name.Name, Tag: reflect.StructTag(tag)})
+			if !ok {
+				continue
+			}
+			fps, goNames = append(fps, p.field(parent, fieldName, field.Type)), append(goNames, name.Name)
+		}
+	}
+	return fps, goNames
+}
+
+// embeddedFields 嵌入的包内结构体的字段,其它包的类型无法解析,忽略
+func (p goSignatureParser) embeddedFields(parent Path, expr ast.Expr) (fps FuncParameters, goNames []string) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	typeExpr, sub, ok := p.named(ident.Name)
+	if !ok {
+		return nil, nil
+	}
+	switch t := typeExpr.(type) {
+	case *ast.StructType:
+		return sub.visibleFields(parent, t)
+	case *ast.Ident, *ast.StarExpr: // type A B
+		return sub.embeddedFields(parent, t)
+	}
+	return nil, nil
+}
+
+// goIdentType go基础类型对应转换协议中的类型,其它包的命名类型为 object
+func goIdentType(name string) (typ string) {
+	switch name {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return TransferUnit_Type_Int
+	case "float32", "float64":
+		return TransferUnit_Type_Number
+	case "bool":
+		return "bool"
+	case "string":
+		return TransferUnit_Type_String
+	case "any":
+		return ""
+	}
+	return "object"
+}
@@ -0,0 +1,154 @@
+package pathtransfer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestParseGoFuncSignatures(t *testing.T) {
+	signatures, err := pathtransfer.ParseGoFuncSignaturesDir("transferfunc")
+	require.NoError(t, err)
+	limit, ok := signatures.Get("transferfunc.Limit")
+	require.True(t, ok)
+	require.Equal(t, "index,size", limit.Input.Names())
+	require.Equal(t, "offset,limit", limit.Output.Names())
+	require.Equal(t, "int", limit.Input[0].Type)
+	require.Equal(t, pathtransfer.Path("func.transferfunc.Limit.output.offset"), limit.Output[0].Path)
+
+	signatures, err = pathtransfer.ParseGoFuncSignatures("user.go", `package user
+
+type Filter struct {
+	Name    string   `+"`json:\"name\"`"+`
+	Ages    []int    `+"`json:\"ages,omitempty\"`"+`
+	Ignored string   `+"`json:\"-\"`"+`
+	private string
+}
+
+type User struct {
+	Name string
+}
+
+func FindUsers(filter *Filter, limit int) ([]User, error) { return nil, nil }
+
+func Count(a, b int) (total int, err error) { return 0, nil }
+
+func unexported() {}
+
+func (f Filter) Method() {}
+`)
+	require.NoError(t, err)
+	require.Len(t, signatures, 2)
+	find, ok := signatures.Get("user.FindUsers")
+	require.True(t, ok)
+	require.Equal(t, "filter,limit", find.Input.Names())
+	require.Equal(t, "object", find.Input[0].Type)
+	require.Equal(t, "name,ages", find.Input[0].Fields.Names())
+	require.Equal(t, "int", find.Input[0].Fields[1].ItemType)
+	require.Equal(t, "out0", find.Output.Names())
+	require.Equal(t, "Name", find.Output[0].Fields.Names())
+	count, _ := signatures.Get("user.Count")
+	require.Equal(t, "a,b", count.Input.Names())
+	require.Equal(t, "total", count.Output.Names())
+
+	t.Run("named and embedded types", func(t *testing.T) {
+		signatures, err := pathtransfer.ParseGoFuncSignatures("order.go", `package order
+
+type ID int64
+
+type Status string
+
+type IDs []ID
+
+type Base struct {
+	ID     ID     `+"`json:\"id\"`"+`
+	Status Status `+"`json:\"status\"`"+`
+}
+
+type Order struct {
+	*Base
+	Status string `+"`json:\"state\"`"+`
+	Name   string `+"`json:\"name\"`"+`
+}
+
+func SetStatus(id ID, status Status, ids IDs) (order Order) { return order }
+`)
+		require.NoError(t, err)
+		setStatus, ok := signatures.Get("order.SetStatus")
+		require.True(t, ok)
+		require.Equal(t, "int", setStatus.Input[0].Type)
+		require.Equal(t, "string", setStatus.Input[1].Type)
+		require.Equal(t, "array", setStatus.Input[2].Type)
+		require.Equal(t, "int", setStatus.Input[2].ItemType)
+		require.Equal(t, "id,state,name", setStatus.Output[0].Fields.Names()) // Base.Status 被 Order.Status 覆盖,同 reflect.VisibleFields
+		require.Equal(t, pathtransfer.Path("func.order.SetStatus.output.order.id"), setStatus.Output[0].Fields[0].Path)
+
+		transfers := pathtransfer.Parse(`
+func.order.SetStatus.input.id@int:data.id
+func.order.SetStatus.input.status@string:data.status
+func.order.SetStatus.input.ids:data.ids
+func.order.SetStatus.output.order.id@int:data.order.id
+func.order.SetStatus.output.order.name:data.order.name
+		`)
+		require.NoError(t, pathtransfer.ValidateFuncTransfers(transfers, signatures))
+
+		registry := pathtransfer.NewFuncRegistry()
+		err = registry.Register("order.SetStatus", func(id orderID, status orderStatus, ids []orderID) (order signatureOrder) { return order }, []string{"id", "status", "ids"}, []string{"order"})
+		require.NoError(t, err)
+		registered, _ := registry.Signatures().Get("order.SetStatus")
+		require.Equal(t, registered.Output[0].Fields.Names(), setStatus.Output[0].Fields.Names())
+	})
+}
+
+type orderID int64
+
+type orderStatus string
+
+type signatureOrderBase struct {
+	ID     orderID     `json:"id"`
+	Status orderStatus `json:"status"`
+}
+
+type signatureOrder struct {
+	*signatureOrderBase
+	Status string `json:"state"`
+	Name   string `json:"name"`
+}
+
+func TestValidateFuncTransfers(t *testing.T) {
+	signatures, err := pathtransfer.ParseGoFuncSignaturesDir("transferfunc")
+	require.NoError(t, err)
+	transfers := pathtransfer.Parse(`
+func.transferfunc.Limit.input.index@int:data.pagination.index
+func.transferfunc.Limit.input.size@int:data.pagination.size
+func.transferfunc.Limit.output.offset@int:data.limit.offset
+func.transferfunc.Limit.output.limit@int:data.limit.size
+	`)
+	require.NoError(t, pathtransfer.ValidateFuncTransfers(transfers, signatures))
+
+	transfers = pathtransfer.Parse(`
+func.transferfunc.Limit.input.index@string:data.pagination.index
+func.transferfunc.Limit.input.pageSize@int:data.pagination.size
+func.transferfunc.Limit.output.offset@number:data.limit.offset
+func.transferfunc.Unknown.input.value:data.name
+	`)
+	err = pathtransfer.ValidateFuncTransfers(transfers, signatures)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_SIGNATURE_UNKNOWN_FUNC)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_SIGNATURE_MISSING_ARG)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_SIGNATURE_EXTRA_ARG)
+	require.ErrorIs(t, err, pathtransfer.ERROR_FUNC_SIGNATURE_TYPE_MISMATCH)
+	require.Len(t, err.(pathtransfer.MultiError), 5)
+	var signatureErr *pathtransfer.FuncSignatureError
+	require.True(t, errors.As(err, &signatureErr))
+	require.Equal(t, "func arg type mismatch: func transferfunc.Limit arg func.transferfunc.Limit.input.index expected int got string", signatureErr.Error())
+
+	t.Run("registry", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.transferfunc.Like.input.value@string:data.name
+func.transferfunc.Like.output.newValue@string:data.name
+		`)
+		require.NoError(t, pathtransfer.ValidateFuncTransfers(transfers, pathtransfer.DefaultFuncRegistry.Signatures()))
+	})
+}
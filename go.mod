@@ -3,6 +3,8 @@ module github.com/suifengpiao14/pathtransfer
 go 1.20
 
 require (
+	github.com/d5/tengo/v2 v2.16.1
+	github.com/dop251/goja v0.0.0-20240220182346-e401ed450204
 	github.com/evanphx/json-patch/v5 v5.9.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cast v1.6.0
@@ -11,17 +13,20 @@ require (
 	github.com/suifengpiao14/gjsonmodifier v0.2.2
 	github.com/tidwall/gjson v1.17.1
 	github.com/tidwall/sjson v1.2.5
+	github.com/yuin/gopher-lua v1.1.1
+	layeh.com/gopher-json v0.0.0-20190114024228-97fed8db8427
 )
 
 require (
-	github.com/d5/tengo/v2 v2.16.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dlclark/regexp2 v1.7.0 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.11.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,236 @@
+package pathtransfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// jsonObject 保持key写入顺序的json对象
+type jsonObject struct {
+	keys []string
+	m    map[string]any
+}
+
+func newJsonObject() *jsonObject {
+	return &jsonObject{
+		keys: make([]string, 0),
+		m:    make(map[string]any),
+	}
+}
+
+func (o *jsonObject) get(key string) (v any) {
+	return o.m[key]
+}
+
+func (o *jsonObject) set(key string, v any) {
+	if _, ok := o.m[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.m[key] = v
+}
+
+type jsonArray struct {
+	items []any
+}
+
+// jsonRaw 原始json值(已经是合法json片段)
+type jsonRaw string
+
+// pathSegment 具体路径的一段,IsIndex 为true时表示数组下标
+type pathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// jsonTreeSet 按路径写入值,路径中不存在的节点自动创建,返回新的根节点
+func jsonTreeSet(node any, segments []pathSegment, v any) (newNode any) {
+	if len(segments) == 0 {
+		return v
+	}
+	seg, rest := segments[0], segments[1:]
+	if seg.IsIndex {
+		arr, ok := node.(*jsonArray)
+		if !ok {
+			arr = &jsonArray{}
+		}
+		for len(arr.items) <= seg.Index {
+			arr.items = append(arr.items, nil)
+		}
+		arr.items[seg.Index] = jsonTreeSet(arr.items[seg.Index], rest, v)
+		return arr
+	}
+	obj, ok := node.(*jsonObject)
+	if !ok {
+		obj = newJsonObject()
+	}
+	obj.set(seg.Key, jsonTreeSet(obj.get(seg.Key), rest, v))
+	return obj
+}
+
+func jsonTreeWrite(w *bytes.Buffer, node any) {
+	switch v := node.(type) {
+	case *jsonObject:
+		w.WriteString("{")
+		for i, k := range v.keys {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			b, _ := json.Marshal(k)
+			w.Write(b)
+			w.WriteString(":")
+			jsonTreeWrite(w, v.m[k])
+		}
+		w.WriteString("}")
+	case *jsonArray:
+		w.WriteString("[")
+		for i, item := range v.items {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			jsonTreeWrite(w, item)
+		}
+		w.WriteString("]")
+	case jsonRaw:
+		w.WriteString(string(v))
+	default:
+		w.WriteString("null")
+	}
+}
+
+// jsonTreeString 将树序列化为json字符串
+func jsonTreeString(node any) (s string) {
+	var w bytes.Buffer
+	jsonTreeWrite(&w, node)
+	return w.String()
+}
+
+// splitPath 按未转义的 . 分割路径,保留转义字符,引号、括号内的 . 不分割(如 gjson 查询 #(user.name=="a"))
+func splitPath(path Path) (segments []string) {
+	segments = make([]string, 0)
+	s := path.String()
+	if s == "" {
+		return segments
+	}
+	start, depth := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++ // 跳过转义字符
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == '.' && depth == 0:
+			segments = append(segments, s[start:i])
+			start = i + 1
+		}
+	}
+	segments = append(segments, s[start:])
+	return segments
+}
+
+// jsonTreeGet 按路径获取节点,不存在返回nil
+func jsonTreeGet(node any, segments []pathSegment) (v any) {
+	for _, seg := range segments {
+		switch n := node.(type) {
+		case *jsonObject:
+			if seg.IsIndex {
+				return nil
+			}
+			node = n.get(seg.Key)
+		case *jsonArray:
+			if !seg.IsIndex || seg.Index >= len(n.items) {
+				return nil
+			}
+			node = n.items[seg.Index]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// unescapePathKey 删除路径key中的转义符
+func unescapePathKey(key string) (newKey string) {
+	if !strings.Contains(key, "\\") {
+		return key
+	}
+	var w strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		w.WriteByte(key[i])
+	}
+	return w.String()
+}
+
+// concretePathSegments 将含 # 的路径按顺序填充数组下标,生成具体路径
+func concretePathSegments(path Path, indexes []int) (segments []pathSegment, err error) {
+	segments = make([]pathSegment, 0)
+	i := 0
+	for _, seg := range splitPath(path) {
+		if seg == "#" {
+			if i >= len(indexes) {
+				err = errors.Errorf("path %s has more # than source array depth %d", path, len(indexes))
+				return nil, err
+			}
+			segments = append(segments, pathSegment{Index: indexes[i], IsIndex: true})
+			i++
+			continue
+		}
+		segments = append(segments, pathSegment{Key: unescapePathKey(seg)})
+	}
+	return segments, nil
+}
+
+// jsonLeaf json 叶子节点(标量、null、空对象、空数组),Path 中数组下标统一为#,具体下标记录在Indexes
+type jsonLeaf struct {
+	Path    Path
+	Indexes []int
+	Raw     string
+}
+
+// getAllJsonLeaf 获取json中所有叶子节点,和gjsonmodifier.GetAllPath 不同,空对象、空数组也作为叶子节点返回
+func getAllJsonLeaf(s string) (leaves []jsonLeaf) {
+	leaves = make([]jsonLeaf, 0)
+	walkJsonLeaf(gjson.Parse(s), nil, nil, func(leaf jsonLeaf) {
+		leaves = append(leaves, leaf)
+	})
+	return leaves
+}
+
+func walkJsonLeaf(result gjson.Result, keys []string, indexes []int, fn func(leaf jsonLeaf)) {
+	isEmptyContainer := result.IsArray() && len(result.Array()) == 0 || result.IsObject() && len(result.Map()) == 0
+	if !(result.IsObject() || result.IsArray()) || isEmptyContainer {
+		fn(jsonLeaf{
+			Path:    Path(strings.Join(keys, ".")),
+			Indexes: append([]int{}, indexes...),
+			Raw:     result.Raw,
+		})
+		return
+	}
+	if result.IsArray() {
+		for i, item := range result.Array() {
+			walkJsonLeaf(item, append(keys, "#"), append(indexes, i), fn)
+		}
+		return
+	}
+	result.ForEach(func(key, value gjson.Result) bool {
+		walkJsonLeaf(value, append(keys, gjson.Escape(key.String())), indexes, fn)
+		return true
+	})
+}
@@ -0,0 +1,164 @@
+package pathtransfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/suifengpiao14/funcs"
+)
+
+const (
+	KeyCase_PascalCase         = "pascalCase"         // 大驼峰
+	KeyCase_KebabCase          = "kebabCase"          // 中划线
+	KeyCase_ScreamingSnakeCase = "screamingSnakeCase" // 大写下划线
+)
+
+// modifyPathSegments 逐层修改路径,数组标识#、动态key*、gjson modifier(@开头)保持不变
+func modifyPathSegments(path Path, fn func(key string) (newKey string)) (newPath Path) {
+	arr := strings.Split(path.String(), ".")
+	for i, key := range arr {
+		if key == "" || key == "#" || key == Path_Wildcard || strings.HasPrefix(key, "@") {
+			continue
+		}
+		arr[i] = fn(key)
+	}
+	newPath = Path(strings.Join(arr, "."))
+	return newPath
+}
+
+// delimitedKey 将中划线、大写下划线格式统一为下划线格式,便于转驼峰
+func delimitedKey(key string) (newKey string) {
+	newKey = strings.ReplaceAll(key, "-", "_")
+	if strings.Contains(newKey, "_") || strings.ToUpper(newKey) == newKey {
+		newKey = strings.ToLower(newKey)
+	}
+	return newKey
+}
+
+// PathModifyFnPascalCase 将路径改成大驼峰格式
+func PathModifyFnPascalCase(path Path) (newPath Path) {
+	return modifyPathSegments(path, func(key string) (newKey string) {
+		return funcs.CamelCase(delimitedKey(key), true, false)
+	})
+}
+
+// PathModifyFnKebabCase 将路径改成中划线格式
+func PathModifyFnKebabCase(path Path) (newPath Path) {
+	return modifyPathSegments(path, func(key string) (newKey string) {
+		return funcs.KebabCase(key)
+	})
+}
+
+// PathModifyFnScreamingSnakeCase 将路径改成大写下划线格式
+func PathModifyFnScreamingSnakeCase(path Path) (newPath Path) {
+	return modifyPathSegments(path, func(key string) (newKey string) {
+		return strings.ToUpper(funcs.SnakeCase(key))
+	})
+}
+
+// PathModifyFnAddKeyPrefixFn 生成每一层key增加前缀的修改函数(PathModifyFnTrimKeyPrefixFn 只剔除匈牙利前缀,两者不互逆)
+func PathModifyFnAddKeyPrefixFn(prefix string) (pathModifyFn PathModifyFn) {
+	return func(path Path) (newPath Path) {
+		return modifyPathSegments(path, func(key string) (newKey string) {
+			return fmt.Sprintf("%s%s", prefix, key)
+		})
+	}
+}
+
+// PathModifyFnCaseFn 根据 KeyCase_XXX 获取路径格式修改函数,不支持的格式返回nil
+func PathModifyFnCaseFn(keyCase string) (pathModifyFn PathModifyFn) {
+	switch keyCase {
+	case KeyCase_CamelCase:
+		return func(path Path) (newPath Path) {
+			return PathModifyFnSmallCameCase(modifyPathSegments(path, delimitedKey))
+		}
+	case KeyCase_SnakeCase:
+		return PathModifyFnSnakeCase
+	case KeyCase_Lower:
+		return PathModifyFnLower
+	case KeyCase_PascalCase:
+		return PathModifyFnPascalCase
+	case KeyCase_KebabCase:
+		return PathModifyFnKebabCase
+	case KeyCase_ScreamingSnakeCase:
+		return PathModifyFnScreamingSnakeCase
+	}
+	return nil
+}
+
+// NamingStrategy 可逆的路径命名策略,Modify 用于转换,Inverse 用于还原(如 ModifyDstPath 后再 Reverse 回原始名称)
+type NamingStrategy struct {
+	Name    string       `json:"name"`
+	Modify  PathModifyFn `json:"-"`
+	Inverse PathModifyFn `json:"-"`
+}
+
+// Reverse 交换转换和还原
+func (ns NamingStrategy) Reverse() (reversed NamingStrategy) {
+	return NamingStrategy{
+		Name:    fmt.Sprintf("reverse(%s)", ns.Name),
+		Modify:  ns.Inverse,
+		Inverse: ns.Modify,
+	}
+}
+
+// NewCaseNamingStrategy 格式转换策略,从 fromKeyCase 转换为 toKeyCase,还原时转回 fromKeyCase(KeyCase_Lower 会丢失信息,不能还原)
+func NewCaseNamingStrategy(fromKeyCase string, toKeyCase string) (namingStrategy NamingStrategy) {
+	return NamingStrategy{
+		Name:    fmt.Sprintf("%s->%s", fromKeyCase, toKeyCase),
+		Modify:  PathModifyFnCaseFn(toKeyCase),
+		Inverse: PathModifyFnCaseFn(fromKeyCase),
+	}
+}
+
+// NewTrimKeyPrefixNamingStrategy 每一层key剔除匈牙利前缀(如 Fuser_id => user_id)
+// 剔除前缀会丢失信息(无法从 user_id 判断原始key是否有前缀),不能还原,Inverse 为nil,组合策略还原时跳过
+func NewTrimKeyPrefixNamingStrategy(prefix string) (namingStrategy NamingStrategy) {
+	return NamingStrategy{
+		Name:   fmt.Sprintf("trimKeyPrefix(%s)", prefix),
+		Modify: PathModifyFnTrimKeyPrefixFn(prefix),
+	}
+}
+
+// NewAddKeyPrefixNamingStrategy 每一层key增加前缀,还原时剔除每一层key的前缀
+func NewAddKeyPrefixNamingStrategy(prefix string) (namingStrategy NamingStrategy) {
+	return NamingStrategy{
+		Name:   fmt.Sprintf("addKeyPrefix(%s)", prefix),
+		Modify: PathModifyFnAddKeyPrefixFn(prefix),
+		Inverse: func(path Path) (newPath Path) {
+			return modifyPathSegments(path, func(key string) (newKey string) {
+				return strings.TrimPrefix(key, prefix)
+			})
+		},
+	}
+}
+
+// NamingStrategies 组合命名策略,按顺序转换,按逆序还原
+type NamingStrategies []NamingStrategy
+
+// Reverse 生成还原策略组合
+func (nss NamingStrategies) Reverse() (reversed NamingStrategies) {
+	reversed = make(NamingStrategies, 0, len(nss))
+	for i := len(nss) - 1; i >= 0; i-- {
+		reversed = append(reversed, nss[i].Reverse())
+	}
+	return reversed
+}
+
+// PathModifyFn 组合成一个路径修改函数
+func (nss NamingStrategies) PathModifyFn() (pathModifyFn PathModifyFn) {
+	return func(path Path) (newPath Path) {
+		newPath = path
+		for _, ns := range nss {
+			if ns.Modify != nil {
+				newPath = ns.Modify(newPath)
+			}
+		}
+		return newPath
+	}
+}
+
+// InversePathModifyFn 组合成一个还原路径的修改函数
+func (nss NamingStrategies) InversePathModifyFn() (pathModifyFn PathModifyFn) {
+	return nss.Reverse().PathModifyFn()
+}
@@ -0,0 +1,61 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestPathModifyFnCase(t *testing.T) {
+	path := pathtransfer.Path("user_info.#.created_at")
+	require.Equal(t, pathtransfer.Path("UserInfo.#.CreatedAt"), pathtransfer.PathModifyFnPascalCase(path))
+	require.Equal(t, pathtransfer.Path("user-info.#.created-at"), pathtransfer.PathModifyFnKebabCase(path))
+	require.Equal(t, pathtransfer.Path("USER_INFO.#.CREATED_AT"), pathtransfer.PathModifyFnScreamingSnakeCase(path))
+	require.Equal(t, pathtransfer.Path("userInfo.#.createdAt"), pathtransfer.PathModifyFnCaseFn(pathtransfer.KeyCase_CamelCase)(pathtransfer.Path("user-info.#.created-at")))
+}
+
+func TestNamingStrategies(t *testing.T) {
+	strategies := pathtransfer.NamingStrategies{
+		pathtransfer.NewTrimKeyPrefixNamingStrategy("F"),
+		pathtransfer.NewCaseNamingStrategy(pathtransfer.KeyCase_SnakeCase, pathtransfer.KeyCase_CamelCase),
+	}
+	transfers := pathtransfer.Parse(`
+Fuser_id:Fuser_id
+Fuser_name:Fuser_name
+Forders.#.Forder_id:Forders.#.Forder_id
+	`)
+	modified := transfers.ModifyDstPath(strategies.PathModifyFn())
+	require.Equal(t, "Fuser_id:userId\nFuser_name:userName\nForders.#.Forder_id:orders.#.orderId\n", modified.String())
+	restored := modified.ModifyDstPath(strategies.InversePathModifyFn())
+	require.Equal(t, "Fuser_id:user_id\nFuser_name:user_name\nForders.#.Forder_id:orders.#.order_id\n", restored.String()) // 剔除前缀不能还原
+
+	t.Run("kebab and pascal", func(t *testing.T) {
+		strategies := pathtransfer.NamingStrategies{
+			pathtransfer.NewCaseNamingStrategy(pathtransfer.KeyCase_KebabCase, pathtransfer.KeyCase_PascalCase),
+			pathtransfer.NewAddKeyPrefixNamingStrategy("X"),
+		}
+		path := pathtransfer.Path("order-list.#.order-id")
+		modified := strategies.PathModifyFn()(path)
+		require.Equal(t, pathtransfer.Path("XOrderList.#.XOrderId"), modified)
+		require.Equal(t, path, strategies.InversePathModifyFn()(modified))
+	})
+	t.Run("trim key prefix", func(t *testing.T) {
+		strategy := pathtransfer.NewTrimKeyPrefixNamingStrategy("F")
+		require.Equal(t, pathtransfer.Path("db.user.user_id"), strategy.Modify("db.user.Fuser_id"))
+		require.Equal(t, pathtransfer.Path("FORM.items.#.name.@tostring"), strategy.Modify("FORM.F_items.#.Fname.@tostring"))
+		require.Nil(t, strategy.Inverse)
+	})
+	t.Run("add key prefix round trip", func(t *testing.T) {
+		path := pathtransfer.Path("db.user.user_id.#.@tostring")
+		modified := pathtransfer.NewAddKeyPrefixNamingStrategy("F").Modify(path)
+		require.Equal(t, pathtransfer.Path("Fdb.Fuser.Fuser_id.#.@tostring"), modified)
+		require.Equal(t, path, pathtransfer.NewAddKeyPrefixNamingStrategy("F").Inverse(modified)) // 还原不依赖转换时的状态
+	})
+	t.Run("screaming snake", func(t *testing.T) {
+		strategy := pathtransfer.NewCaseNamingStrategy(pathtransfer.KeyCase_CamelCase, pathtransfer.KeyCase_ScreamingSnakeCase)
+		path := pathtransfer.Path("order.createdAt")
+		require.Equal(t, pathtransfer.Path("ORDER.CREATED_AT"), strategy.Modify(path))
+		require.Equal(t, path, strategy.Inverse(strategy.Modify(path)))
+	})
+}
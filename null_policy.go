@@ -0,0 +1,103 @@
+package pathtransfer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_TRANSFER_NULL_POLICY = errors.New("unknown null policy")
+)
+
+const (
+	NullPolicy_Keep        = "keep"        // null 原样输出(默认)
+	NullPolicy_Drop        = "drop"        // null 不输出,和来源不存在一致
+	NullPolicy_Zero        = "zero"        // null 输出目标类型的零值,目标没有类型时保持null
+	NullPolicy_EmptyAsNull = "emptyAsNull" // 空字符串视为null,可以和其它策略组合,如 emptyAsNull,drop
+	// NullPolicy_EmptyArrayAsNull 空数组视为null,可以和其它策略组合,如 emptyArrayAsNull,drop;零值为null(数组没有转换类型)
+	NullPolicy_EmptyArrayAsNull = "emptyArrayAsNull"
+)
+
+// NullPolicy null、空字符串的处理策略,多个策略用 , 分隔,转换协议中以 null= 放在目标之后,如 user.nick:Api.nick null=emptyAsNull,drop
+// 来源不存在时目标始终不输出,便于调用方区分"清空"(null)和"未传"(不存在)
+type NullPolicy string
+
+func (np NullPolicy) String() string {
+	return string(np)
+}
+
+// Has 判断是否包含策略
+func (np NullPolicy) Has(policy string) bool {
+	for _, p := range strings.Split(np.String(), ",") {
+		if strings.EqualFold(strings.TrimSpace(p), policy) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验策略,不支持的策略返回 ERROR_TRANSFER_NULL_POLICY
+func (np NullPolicy) Validate() (err error) {
+	if np == "" {
+		return nil
+	}
+	for _, p := range strings.Split(np.String(), ",") {
+		switch strings.ToLower(strings.TrimSpace(p)) {
+		case NullPolicy_Keep, NullPolicy_Drop, NullPolicy_Zero, strings.ToLower(NullPolicy_EmptyAsNull), strings.ToLower(NullPolicy_EmptyArrayAsNull):
+		default:
+			err = errors.WithMessagef(ERROR_TRANSFER_NULL_POLICY, "got:%s", p)
+			return err
+		}
+	}
+	return nil
+}
+
+// zeroValues 转换函数对应的零值
+var zeroValues = map[string]string{
+	".@tonum":    "0",
+	".@tobool":   "false",
+	".@tostring": `""`,
+}
+
+// zeroRaw 目标类型的零值,未知类型返回null
+func zeroRaw(typ string) (raw string) {
+	transferType, ok := DefaultTransferTypes.GetByType(typ)
+	if !ok {
+		return "null"
+	}
+	raw, ok = zeroValues[transferType.ConvertFn]
+	if !ok {
+		return "null"
+	}
+	return raw
+}
+
+// applyNullPolicy 按策略处理取值结果中的null、空字符串、空数组,# 展开的数组逐个元素处理
+func (rv resolvedValue) applyNullPolicy(policy NullPolicy, typ string) (newRv resolvedValue) {
+	if policy == "" || !rv.exists {
+		return rv
+	}
+	if rv.isArray {
+		newRv = resolvedValue{exists: true, isArray: true, items: make([]resolvedValue, 0, len(rv.items))}
+		for _, item := range rv.items {
+			newRv.items = append(newRv.items, item.applyNullPolicy(policy, typ))
+		}
+		return newRv
+	}
+	result := gjson.Parse(rv.raw)
+	isNull := result.Type == gjson.Null ||
+		policy.Has(NullPolicy_EmptyAsNull) && result.Type == gjson.String && result.Str == "" ||
+		policy.Has(NullPolicy_EmptyArrayAsNull) && result.IsArray() && len(result.Array()) == 0
+	if !isNull {
+		return rv
+	}
+	switch {
+	case policy.Has(NullPolicy_Drop):
+		return resolvedValue{}
+	case policy.Has(NullPolicy_Zero):
+		return resolvedValue{exists: true, raw: zeroRaw(typ)}
+	}
+	return resolvedValue{exists: true, raw: "null"}
+}
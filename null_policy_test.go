@@ -0,0 +1,83 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyNullPolicy(t *testing.T) {
+	data := `{"user":{"name":"张三","nick":"","email":null,"age":null,"vip":null},"items":[{"price":null},{"price":"2"},{}]}`
+	transfers := pathtransfer.Parse(`
+user.name:Api.name
+user.nick:Api.nick null=emptyAsNull
+user.email:Api.email null=drop
+user.age:Api.age@int null=zero
+user.vip:Api.vip@bool null=zero
+user.nick:Api.nickname@string null=emptyAsNull,zero
+items.#.price:Api.items.#.price@number null=zero
+user.phone:Api.phone null=zero
+user.email:Api.mail
+	`)
+	require.Equal(t, pathtransfer.NullPolicy("emptyAsNull,zero"), transfers[5].NullPolicy)
+	require.Equal(t, "user.nick:Api.nickname@string null=emptyAsNull,zero", transfers[5].String())
+	out, err := transfers.Apply([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{"name":"张三","nick":null,"age":0,"vip":false,"nickname":"","items":[{"price":0},{"price":2}],"mail":null}}`
+	require.JSONEq(t, expected, string(out))
+
+	gjsonOut := gjson.Get(data, transfers[:6].GjsonPath()).String()
+	require.JSONEq(t, `{"Api":{"name":"张三","nick":null,"age":0,"vip":false,"nickname":""}}`, gjsonOut)
+
+	t.Run("global", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+user.nick:Api.nick
+user.email:Api.email
+user.age:Api.age@int null=keep
+user.phone:Api.phone
+		`)
+		out, _, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{NullPolicy: "emptyAsNull,drop"})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Api":{"age":null}}`, string(out))
+	})
+	t.Run("with when", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`user.email:Api.email@string null=zero when user.name=="张三"`)
+		require.Equal(t, `user.name=="张三"`, transfers[0].When)
+		require.Equal(t, pathtransfer.NullPolicy("zero"), transfers[0].NullPolicy)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Api":{"email":""}}`, string(out))
+	})
+	t.Run("when before null", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`user.email:Api.email@string when user.name=="张三" null=zero`)
+		require.Equal(t, `user.name=="张三"`, transfers[0].When)
+		require.Equal(t, pathtransfer.NullPolicy("zero"), transfers[0].NullPolicy)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Api":{"email":""}}`, string(out))
+	})
+	t.Run("empty array", func(t *testing.T) {
+		data := `{"tags":[],"roles":[],"ids":[1]}`
+		transfers := pathtransfer.Parse(`
+tags:Api.tags null=emptyArrayAsNull
+roles:Api.roles null=emptyArrayAsNull,drop
+ids:Api.ids null=emptyArrayAsNull,drop
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Api":{"tags":null,"ids":[1]}}`, string(out))
+	})
+	t.Run("unknown policy", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`user.nick:Api.nick null=emptyAsNul`)
+		_, err := transfers.Apply([]byte(data))
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_NULL_POLICY)
+
+		_, _, err = pathtransfer.Parse(`user.nick:Api.nick`).ApplyWithOption([]byte(data), pathtransfer.ApplyOption{NullPolicy: "drop,unknown"})
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_NULL_POLICY)
+
+		_, err = pathtransfer.Unmarshal(`[{"src":{"path":"user.nick"},"dst":{"path":"Api.nick"},"nullPolicy":"bad"}]`)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_NULL_POLICY)
+	})
+}
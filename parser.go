@@ -3,6 +3,8 @@ package pathtransfer
 import (
 	"encoding/json"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 type TransferLine string
@@ -15,6 +17,14 @@ func (l TransferLine) Transfer() (ts Transfers) {
 line stransfer example:
 api.getUser.input.id@int:db.user.Fuser_id@int
 api.getUser.input.name:db.user.Fname
+=price*qty:api.order.amount@int
+="${firstName} ${lastName}":api.user.fullName
+api.order.discount:db.order.Fdiscount when api.order.type=="vip"
+api.user.user_name??api.user.userName??api.user.name:db.user.Fname
+api.order.items|groupBy(type):db.order.itemsByType
+api.order.items.#.price|sum:db.order.Ftotal
+api.user.nickname:db.user.Fnickname null=emptyAsNull,drop
+api.user.tags:db.user.Ftags null=emptyArrayAsNull,drop
 **/
 
 func Parse(s string) (ts Transfers) {
@@ -26,13 +36,31 @@ func Parse(s string) (ts Transfers) {
 		if row == "" {
 			continue
 		}
-		var src, dst, srcType, dstType string
+		var src, dst, srcType, dstType, expr string
+		var fallback []Path
+		row, when, nullPolicy := parseTransferSuffix(row) // 条件子句、null处理策略
 		src, dst = row, row
-		colonIndex := strings.Index(src, ":")
+		colonIndex := colonAtIndex(src)
 		if colonIndex > -1 {
 			dst = src[colonIndex+1:]
 			src = src[:colonIndex]
 		}
+		src, pipes := parseTransferPipes(src)             // 后置处理
+		if strings.HasPrefix(src, Transfer_Expr_Prefix) { // 计算表达式
+			expr = strings.TrimSpace(strings.TrimPrefix(src, Transfer_Expr_Prefix))
+			src = ""
+		}
+		if expr == "" && strings.Contains(src, Transfer_Coalesce_Separator) { // 备选来源路径
+			paths := strings.Split(src, Transfer_Coalesce_Separator)
+			src = strings.TrimSpace(paths[0])
+			for _, path := range paths[1:] {
+				path = strings.TrimSpace(path)
+				if atIndex := typeAtIndex(path); atIndex > -1 {
+					srcType, path = path[atIndex+1:], path[:atIndex]
+				}
+				fallback = append(fallback, Path(path))
+			}
+		}
 		srcAtIndex := typeAtIndex(src)
 		if srcAtIndex > -1 {
 			srcType = src[srcAtIndex+1:]
@@ -52,12 +80,71 @@ func Parse(s string) (ts Transfers) {
 				Path: Path(dst),
 				Type: dstType,
 			},
+			Expr:       expr,
+			When:       when,
+			Fallback:   fallback,
+			Pipes:      pipes,
+			NullPolicy: nullPolicy,
 		}
 		ts = append(ts, t)
 	}
 	return ts
 }
 
+// colonAtIndex 来源和目标的分隔符位置,引号、括号内的 : 不计入(如表达式 ="a:b" 、gjson 路径 {a:b})
+func colonAtIndex(row string) (colonIndex int) {
+	return topLevelIndex(row, ":")
+}
+
+// parseTransferSuffix 剥离行尾的条件子句(when)和null处理策略(null=),两者顺序不限
+func parseTransferSuffix(row string) (rest string, when string, nullPolicy NullPolicy) {
+	rest = row
+	for {
+		whenIndex, nullIndex := whenAtIndex(rest), topLevelIndex(rest, Transfer_Null_Keyword)
+		switch {
+		case whenIndex > -1 && whenIndex > nullIndex && when == "":
+			when = strings.TrimSpace(rest[whenIndex+len(Transfer_When_Keyword):])
+			rest = strings.TrimSpace(rest[:whenIndex])
+		case nullIndex > -1 && nullPolicy == "":
+			nullPolicy = NullPolicy(strings.TrimSpace(rest[nullIndex+len(Transfer_Null_Keyword):]))
+			rest = strings.TrimSpace(rest[:nullIndex])
+		default:
+			return rest, when, nullPolicy
+		}
+	}
+}
+
+// whenAtIndex 条件子句位置(src:dst when expr)
+func whenAtIndex(row string) (whenIndex int) {
+	return topLevelIndex(row, Transfer_When_Keyword)
+}
+
+// topLevelIndex 查找引号、括号外的分隔符位置,单引号仅在表达式中作为引号
+func topLevelIndex(row string, sep string) (index int) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(row); i++ {
+		c := row[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'' && strings.HasPrefix(row, Transfer_Expr_Prefix):
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(row[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
 func typeAtIndex(path string) (typeAtIndex int) {
 	typeAtIndex = -1
 	for i := len(path) - 1; i >= 0; i-- {
@@ -69,13 +156,19 @@ func typeAtIndex(path string) (typeAtIndex int) {
 	return typeAtIndex
 }
 
-//Unmarshal 转换为Transfers对象
+// Unmarshal 转换为Transfers对象,校验null处理策略
 func Unmarshal(tJson string) (vocabularies Transfers, err error) {
 	vocabularies = make(Transfers, 0)
 	err = json.Unmarshal([]byte(tJson), &vocabularies)
 	if err != nil {
 		return nil, err
 	}
+	for _, t := range vocabularies {
+		if err = t.NullPolicy.Validate(); err != nil {
+			err = errors.WithMessagef(err, "transfer %s", t.String())
+			return nil, err
+		}
+	}
 	return vocabularies, nil
 }
 func Marshal(tJson string) (vocabularies Transfers, err error) {
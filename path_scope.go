@@ -0,0 +1,98 @@
+package pathtransfer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PathScope 路径作用域,标记路径中哪些层级允许被修改,配合 Wrap 限定 PathModifyFn 的修改范围
+type PathScope func(keys []string) (selected []bool)
+
+// Wrap 将修改函数限定在作用域内,连续选中的层级作为一个子路径传给修改函数,未选中的层级(包括作用域未返回的层级)保持不变
+func (scope PathScope) Wrap(fn PathModifyFn) (pathModifyFn PathModifyFn) {
+	return func(path Path) (newPath Path) {
+		if fn == nil || path == "" {
+			return path
+		}
+		keys := strings.Split(path.String(), ".")
+		selected := scope(keys)
+		isSelected := func(i int) bool {
+			return i < len(selected) && selected[i]
+		}
+		arr := make([]string, 0)
+		for i := 0; i < len(keys); i++ {
+			if !isSelected(i) {
+				arr = append(arr, keys[i])
+				continue
+			}
+			j := i
+			for j < len(keys) && isSelected(j) {
+				j++
+			}
+			subPath := fn(Path(strings.Join(keys[i:j], ".")))
+			if subPath != "" {
+				arr = append(arr, subPath.String())
+			}
+			i = j - 1
+		}
+		newPath = Path(strings.Join(arr, "."))
+		return newPath
+	}
+}
+
+// PathScopeNamespace 只修改命名空间之后的部分,如 Dictionary.user_id 只修改 user_id,不属于该命名空间的路径不修改
+func PathScopeNamespace(namespace string) (scope PathScope) {
+	nsKeys := strings.Split(strings.Trim(namespace, "."), ".")
+	return func(keys []string) (selected []bool) {
+		selected = make([]bool, len(keys))
+		if len(keys) <= len(nsKeys) {
+			return selected
+		}
+		for i, nsKey := range nsKeys {
+			if keys[i] != nsKey {
+				return selected
+			}
+		}
+		for i := len(nsKeys); i < len(keys); i++ {
+			selected[i] = true
+		}
+		return selected
+	}
+}
+
+// PathScopeLeaf 只修改最后一层key,结尾的gjson modifier(@开头)不计入;结尾的数组标识#和最后一层key一起传给修改函数,
+// 如 ids.# 增加 @tostring 得到 ids.#.@tostring
+var PathScopeLeaf PathScope = func(keys []string) (selected []bool) {
+	selected = make([]bool, len(keys))
+	end := len(keys)
+	for end > 0 && strings.HasPrefix(keys[end-1], "@") {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		selected[i] = true
+		if keys[i] != "#" {
+			break
+		}
+	}
+	return selected
+}
+
+// PathScopeMatch 只修改匹配正则的层级
+func PathScopeMatch(pattern *regexp.Regexp) (scope PathScope) {
+	return func(keys []string) (selected []bool) {
+		selected = make([]bool, len(keys))
+		for i, key := range keys {
+			selected[i] = key != "#" && pattern.MatchString(key)
+		}
+		return selected
+	}
+}
+
+// Scope 将命名策略的转换和还原都限定在作用域内
+func (ns NamingStrategy) Scope(scope PathScope) (scoped NamingStrategy) {
+	return NamingStrategy{
+		Name:    ns.Name,
+		Modify:  scope.Wrap(ns.Modify),
+		Inverse: scope.Wrap(ns.Inverse),
+	}
+}
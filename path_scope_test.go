@@ -0,0 +1,44 @@
+package pathtransfer_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestPathScope(t *testing.T) {
+	t.Run("namespace", func(t *testing.T) {
+		fn := pathtransfer.PathScopeNamespace("Dictionary").Wrap(pathtransfer.PathModifyFnSmallCameCase)
+		require.Equal(t, pathtransfer.Path("Dictionary.userInfo.#.userId"), fn("Dictionary.user_info.#.user_id"))
+		require.Equal(t, pathtransfer.Path("Api.user_info"), fn("Api.user_info"))
+		lower := pathtransfer.PathScopeNamespace("Api.getUser").Wrap(pathtransfer.PathModifyFnLower)
+		require.Equal(t, pathtransfer.Path("Api.getUser.username"), lower("Api.getUser.UserName"))
+	})
+	t.Run("leaf", func(t *testing.T) {
+		fn := pathtransfer.PathScopeLeaf.Wrap(pathtransfer.PathModifyFnSnakeCase)
+		require.Equal(t, pathtransfer.Path("Dictionary.userInfo.user_id"), fn("Dictionary.userInfo.userId"))
+		require.Equal(t, pathtransfer.Path("Dictionary.server_ids.#"), fn("Dictionary.serverIds.#"))
+		toString := pathtransfer.PathScopeLeaf.Wrap(pathtransfer.PathModifyFnString)
+		require.Equal(t, pathtransfer.Path("ids.#.@tostring"), toString("ids.#"))
+	})
+	t.Run("short selected", func(t *testing.T) {
+		scope := pathtransfer.PathScope(func(keys []string) (selected []bool) {
+			return []bool{true}
+		})
+		fn := scope.Wrap(pathtransfer.PathModifyFnSnakeCase)
+		require.Equal(t, pathtransfer.Path("user_info.userId"), fn("userInfo.userId"))
+	})
+	t.Run("match", func(t *testing.T) {
+		fn := pathtransfer.PathScopeMatch(regexp.MustCompile(`^F[a-z]+_[a-z_]+$`)).Wrap(pathtransfer.PathModifyFnTrimKeyPrefixFn("F"))
+		require.Equal(t, pathtransfer.Path("Form.user.user_id"), fn("Form.user.Fuser_id"))
+	})
+	t.Run("naming strategy", func(t *testing.T) {
+		strategy := pathtransfer.NewCaseNamingStrategy(pathtransfer.KeyCase_SnakeCase, pathtransfer.KeyCase_CamelCase).Scope(pathtransfer.PathScopeNamespace("Dictionary"))
+		transfers := pathtransfer.Parse(`db.user.Fuser_id:Dictionary.user_id`)
+		modified := transfers.ModifyDstPath(strategy.Modify)
+		require.Equal(t, "db.user.Fuser_id:Dictionary.userId\n", modified.String())
+		require.Equal(t, transfers.String(), modified.ModifyDstPath(strategy.Inverse).String())
+	})
+}
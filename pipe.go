@@ -0,0 +1,442 @@
+package pathtransfer
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ERROR_TRANSFER_PIPE_NOT_FOUND = errors.New("transfer pipe not found")
+	ERROR_TRANSFER_PIPE_INPUT     = errors.New("transfer pipe input invalid")
+)
+
+// TransferPipe 来源值的后置处理,转换协议中以 |name(arg1,arg2) 的形式跟在来源路径后面,如 items|groupBy(type):Api.itemsByType
+type TransferPipe struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+func (p TransferPipe) String() string {
+	if len(p.Args) == 0 {
+		return fmt.Sprintf("%s%s", Transfer_Pipe_Separator, p.Name)
+	}
+	args := make([]string, 0, len(p.Args))
+	for _, arg := range p.Args {
+		if arg == "" || arg != strings.TrimSpace(arg) || strings.ContainsAny(arg, `,"()|`) { // 包含分隔符、空格的参数加引号
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return fmt.Sprintf("%s%s(%s)", Transfer_Pipe_Separator, p.Name, strings.Join(args, ","))
+}
+
+type TransferPipes []TransferPipe
+
+func (ps TransferPipes) String() string {
+	var w bytes.Buffer
+	for _, p := range ps {
+		w.WriteString(p.String())
+	}
+	return w.String()
+}
+
+// Call 依次执行
+func (ps TransferPipes) Call(value any) (newValue any, err error) {
+	newValue = value
+	for _, p := range ps {
+		fn, ok := transferPipeFn(p.Name)
+		if !ok {
+			err = errors.WithMessagef(ERROR_TRANSFER_PIPE_NOT_FOUND, "name:%s", p.Name)
+			return nil, err
+		}
+		newValue, err = fn(newValue, p.Args...)
+		if err != nil {
+			err = errors.WithMessagef(err, "pipe %s", p.String())
+			return nil, err
+		}
+	}
+	return newValue, nil
+}
+
+const (
+	Transfer_Pipe_Separator = "|" // 转换协议中来源值后置处理分隔符
+)
+
+// TransferPipeFn 来源值后置处理函数,value 为来源值(#展开的数组为[]any,不存在的元素为nil)
+type TransferPipeFn func(value any, args ...string) (newValue any, err error)
+
+// transferPipeFns 可用的后置处理函数,通过 RegisterTransferPipe 扩展
+var transferPipeFns = map[string]TransferPipeFn{
+	"flatten": pipeFlatten,
+	"groupBy": pipeGroupBy,
+	"zip":     pipeZip,
+	"pivot":   pipePivot,
+	"count":   pipeCount,
+	"sum":     pipeSum,
+	"min":     pipeMin,
+	"max":     pipeMax,
+	"avg":     pipeAvg,
+	"join":    pipeJoin,
+}
+
+// transferPipeTypes 后置处理结果的默认类型,目标没有指定类型时使用,按 DefaultTransferTypes 转换
+var transferPipeTypes = map[string]string{
+	"count": TransferUnit_Type_Int,
+	"sum":   TransferUnit_Type_Number,
+	"min":   TransferUnit_Type_Number,
+	"max":   TransferUnit_Type_Number,
+	"avg":   TransferUnit_Type_Number,
+	"join":  TransferUnit_Type_String,
+}
+
+// transferPipesMu 保护 transferPipeFns、transferPipeTypes,注册和转换可能并发
+var transferPipesMu sync.RWMutex
+
+// RegisterTransferPipe 注册来源值后置处理函数,typ 为结果的默认类型,可以为空
+func RegisterTransferPipe(name string, typ string, fn TransferPipeFn) {
+	transferPipesMu.Lock()
+	defer transferPipesMu.Unlock()
+	transferPipeFns[name] = fn
+	if typ != "" {
+		transferPipeTypes[name] = typ
+	}
+}
+
+func transferPipeFn(name string) (fn TransferPipeFn, ok bool) {
+	transferPipesMu.RLock()
+	defer transferPipesMu.RUnlock()
+	fn, ok = transferPipeFns[name]
+	return fn, ok
+}
+
+// Type 结果的默认类型,取最后一个后置处理注册的类型
+func (ps TransferPipes) Type() (typ string) {
+	if len(ps) == 0 {
+		return ""
+	}
+	transferPipesMu.RLock()
+	defer transferPipesMu.RUnlock()
+	return transferPipeTypes[ps[len(ps)-1].Name]
+}
+
+var transferPipeReg = regexp.MustCompile(`^(\w+)(?:\((.*)\))?$`)
+
+// parseTransferPipes 从来源路径中拆分后置处理,只识别已注册的名称,避免和 gjson 路径中的 | 冲突
+// 参数以 , 分隔,包含 , 或首尾空格的参数使用双引号,如 join(", ")
+func parseTransferPipes(src string) (path string, pipes TransferPipes) {
+	path = src
+	for {
+		index := lastPipeIndex(path)
+		if index < 0 {
+			break
+		}
+		matches := transferPipeReg.FindStringSubmatch(strings.TrimSpace(path[index+1:]))
+		if matches == nil {
+			break
+		}
+		if _, ok := transferPipeFn(matches[1]); !ok {
+			break
+		}
+		pipe := TransferPipe{Name: matches[1]}
+		if matches[2] != "" {
+			pipe.Args = splitPipeArgs(matches[2])
+		}
+		pipes = append(TransferPipes{pipe}, pipes...)
+		path = strings.TrimSpace(path[:index])
+	}
+	return path, pipes
+}
+
+// lastPipeIndex 最后一个不在双引号内的 |
+func lastPipeIndex(path string) (index int) {
+	index = -1
+	inQuote := false
+	for i := 0; i < len(path); i++ {
+		switch {
+		case inQuote && path[i] == '\\':
+			i++
+		case path[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && strings.HasPrefix(path[i:], Transfer_Pipe_Separator):
+			index = i
+		}
+	}
+	return index
+}
+
+// splitPipeArgs 按双引号外的 , 拆分参数,双引号参数按 go 字符串字面量解析
+func splitPipeArgs(s string) (args []string) {
+	inQuote := false
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch {
+			case inQuote && s[i] == '\\':
+				i++
+				continue
+			case s[i] == '"':
+				inQuote = !inQuote
+				continue
+			case inQuote || s[i] != ',':
+				continue
+			}
+		}
+		arg := strings.TrimSpace(s[start:i])
+		if unquoted, err := strconv.Unquote(arg); err == nil && strings.HasPrefix(arg, `"`) {
+			arg = unquoted
+		}
+		args = append(args, arg)
+		start = i + 1
+	}
+	return args
+}
+
+func pipeArray(value any) (arr []any, err error) {
+	switch v := value.(type) {
+	case nil:
+		return []any{}, nil
+	case []any:
+		return v, nil
+	}
+	err = errors.WithMessagef(ERROR_TRANSFER_PIPE_INPUT, "array required,got:%T", value)
+	return nil, err
+}
+
+// pipeItemGet 获取数组元素中路径对应的值
+func pipeItemGet(item any, path string) (value any) {
+	raw, err := exprValueRaw(item)
+	if err != nil {
+		return nil
+	}
+	result := gjson.Get(raw, path)
+	if !result.Exists() {
+		return nil
+	}
+	return result.Value()
+}
+
+// pipeFlatten 展开嵌套数组,args[0] 为展开深度,默认完全展开,null 元素剔除
+func pipeFlatten(value any, args ...string) (newValue any, err error) {
+	arr, err := pipeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	depth := -1
+	if len(args) > 0 {
+		depth = cast.ToInt(args[0])
+	}
+	return flattenArray(arr, depth), nil
+}
+
+func flattenArray(arr []any, depth int) (flat []any) {
+	flat = make([]any, 0)
+	for _, item := range arr {
+		switch v := item.(type) {
+		case nil:
+		case []any:
+			if depth == 0 {
+				flat = append(flat, v)
+				continue
+			}
+			flat = append(flat, flattenArray(v, depth-1)...)
+		default:
+			flat = append(flat, v)
+		}
+	}
+	return flat
+}
+
+// pipeGroupBy 按元素中 args[0] 路径的值分组,输出 {key:[item...]}
+func pipeGroupBy(value any, args ...string) (newValue any, err error) {
+	if len(args) != 1 {
+		err = errors.WithMessagef(ERROR_TRANSFER_PIPE_INPUT, "groupBy require 1 arg,got:%d", len(args))
+		return nil, err
+	}
+	arr, err := pipeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any)
+	for _, item := range arr {
+		if item == nil {
+			continue
+		}
+		key := cast.ToString(pipeItemGet(item, args[0]))
+		group, _ := m[key].([]any)
+		m[key] = append(group, item)
+	}
+	return m, nil
+}
+
+// pipeZip 将对象中的并列数组合并成对象数组,如 {name:[a,b],age:[1,2]} => [{name:a,age:1},{name:b,age:2}],args 指定参与合并的key,默认全部
+func pipeZip(value any, args ...string) (newValue any, err error) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		err = errors.WithMessagef(ERROR_TRANSFER_PIPE_INPUT, "zip require object of arrays,got:%T", value)
+		return nil, err
+	}
+	keys := args
+	if len(keys) == 0 {
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	size := 0
+	columns := make(map[string][]any)
+	for _, key := range keys {
+		column, err := pipeArray(m[key])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "key:%s", key)
+		}
+		columns[key] = column
+		if len(column) > size {
+			size = len(column)
+		}
+	}
+	arr := make([]any, 0, size)
+	for i := 0; i < size; i++ {
+		item := make(map[string]any)
+		for _, key := range keys {
+			if column := columns[key]; i < len(column) && column[i] != nil {
+				item[key] = column[i]
+			}
+		}
+		arr = append(arr, item)
+	}
+	return arr, nil
+}
+
+// pipePivot 将 [{key:k,value:v}] 转换为 {k:v},args 为key、value 的路径,默认 key、value
+func pipePivot(value any, args ...string) (newValue any, err error) {
+	arr, err := pipeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	keyPath, valuePath := "key", "value"
+	if len(args) > 0 {
+		keyPath = args[0]
+	}
+	if len(args) > 1 {
+		valuePath = args[1]
+	}
+	m := make(map[string]any)
+	for _, item := range arr {
+		key := pipeItemGet(item, keyPath)
+		if key == nil {
+			continue
+		}
+		m[cast.ToString(key)] = pipeItemGet(item, valuePath)
+	}
+	return m, nil
+}
+
+// aggregateItems 聚合的元素,嵌套数组展开,不存在的元素剔除
+func aggregateItems(value any) (items []any, err error) {
+	arr, err := pipeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	return flattenArray(arr, -1), nil
+}
+
+// aggregateNumbers 聚合的数值元素
+func aggregateNumbers(value any) (numbers []float64, err error) {
+	items, err := aggregateItems(value)
+	if err != nil {
+		return nil, err
+	}
+	numbers = make([]float64, 0, len(items))
+	for _, item := range items {
+		f, err := cast.ToFloat64E(item)
+		if err != nil {
+			err = errors.WithMessagef(ERROR_TRANSFER_PIPE_INPUT, "number required,got:%v", item)
+			return nil, err
+		}
+		numbers = append(numbers, f)
+	}
+	return numbers, nil
+}
+
+// pipeCount 元素个数
+func pipeCount(value any, args ...string) (newValue any, err error) {
+	items, err := aggregateItems(value)
+	if err != nil {
+		return nil, err
+	}
+	return len(items), nil
+}
+
+// pipeSum 求和,没有元素时为0
+func pipeSum(value any, args ...string) (newValue any, err error) {
+	numbers, err := aggregateNumbers(value)
+	if err != nil {
+		return nil, err
+	}
+	sum := float64(0)
+	for _, f := range numbers {
+		sum += f
+	}
+	return sum, nil
+}
+
+// pipeMin 最小值,没有元素时不存在
+func pipeMin(value any, args ...string) (newValue any, err error) {
+	return aggregateCompare(value, func(a, b float64) bool { return a < b })
+}
+
+// pipeMax 最大值,没有元素时不存在
+func pipeMax(value any, args ...string) (newValue any, err error) {
+	return aggregateCompare(value, func(a, b float64) bool { return a > b })
+}
+
+func aggregateCompare(value any, better func(a, b float64) bool) (newValue any, err error) {
+	numbers, err := aggregateNumbers(value)
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+	result := numbers[0]
+	for _, f := range numbers[1:] {
+		if better(f, result) {
+			result = f
+		}
+	}
+	return result, nil
+}
+
+// pipeAvg 平均值,没有元素时不存在
+func pipeAvg(value any, args ...string) (newValue any, err error) {
+	numbers, err := aggregateNumbers(value)
+	if err != nil || len(numbers) == 0 {
+		return nil, err
+	}
+	sum := float64(0)
+	for _, f := range numbers {
+		sum += f
+	}
+	return sum / float64(len(numbers)), nil
+}
+
+// pipeJoin 拼接成字符串,args[0] 为分隔符,默认 ,
+func pipeJoin(value any, args ...string) (newValue any, err error) {
+	items, err := aggregateItems(value)
+	if err != nil {
+		return nil, err
+	}
+	sep := ","
+	if len(args) > 0 {
+		sep = args[0]
+	}
+	arr := make([]string, 0, len(items))
+	for _, item := range items {
+		arr = append(arr, cast.ToString(item))
+	}
+	return strings.Join(arr, sep), nil
+}
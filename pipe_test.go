@@ -0,0 +1,97 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyPipes(t *testing.T) {
+	data := `{
+		"services":[{"name":"a","servers":[{"url":"a1"},{"url":"a2"}]},{"name":"b","servers":[{"url":"b1"}]}],
+		"items":[{"type":"book","id":1},{"type":"pen","id":2},{"type":"book","id":3}],
+		"names":["张三","李四"],"ages":[18,20],
+		"attrs":[{"key":"color","value":"red"},{"key":"size","value":"L"}],
+		"props":[{"k":"weight","v":1.5}]
+	}`
+	transfers := pathtransfer.Parse(`
+services.#.servers.#.url|flatten:Api.urls
+services.#.servers|flatten:Api.servers.#
+items|groupBy(type):Api.itemsByType
+{name:names,age:ages}|zip:Api.users
+attrs|pivot:Api.attrs
+props|pivot(k,v):Api.props
+	`)
+	require.Equal(t, pathtransfer.TransferPipes{{Name: "groupBy", Args: []string{"type"}}}, transfers[2].Pipes)
+	require.Equal(t, "items|groupBy(type):Api.itemsByType", transfers[2].String())
+	require.Equal(t, pathtransfer.Path("{name:names,age:ages}"), transfers[3].Src.Path)
+
+	out, err := transfers.Apply([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{
+		"urls":["a1","a2","b1"],
+		"servers":[{"url":"a1"},{"url":"a2"},{"url":"b1"}],
+		"itemsByType":{"book":[{"type":"book","id":1},{"type":"book","id":3}],"pen":[{"type":"pen","id":2}]},
+		"users":[{"name":"张三","age":18},{"name":"李四","age":20}],
+		"attrs":{"color":"red","size":"L"},
+		"props":{"weight":1.5}
+	}}`
+	require.JSONEq(t, expected, string(out))
+
+	gjsonOut := gjson.Get(data, transfers.GjsonPath()).String()
+	require.JSONEq(t, expected, gjsonOut)
+
+	t.Run("gjson pipe kept", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`items.#.id|@reverse:Api.ids`)
+		require.Empty(t, transfers[0].Pipes)
+	})
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := pathtransfer.Parse(`names|pivot:Api.x`).Apply([]byte(`{"names":"a"}`))
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PIPE_INPUT)
+	})
+}
+
+func TestApplyAggregate(t *testing.T) {
+	data := `{"items":[{"name":"a","price":"2.5","qty":2},{"name":"b","price":10,"qty":1},{"name":"c","qty":3}],"groups":[{"scores":[1,2]},{"scores":[3]}]}`
+	transfers := pathtransfer.Parse(`
+items|count:Api.order.count
+items.#.price|sum:Api.order.total
+items.#.price|min:Api.order.minPrice
+items.#.price|max:Api.order.maxPrice@string
+items.#.qty|avg:Api.order.avgQty
+items.#.name|join(/):Api.order.names
+groups.#.scores.#|sum:Api.scoreTotal
+items.#.price|count:Api.order.priced
+	`)
+	out, err := transfers.Apply([]byte(data))
+	require.NoError(t, err)
+	expected := `{"Api":{"order":{"count":3,"total":12.5,"minPrice":2.5,"maxPrice":"10","avgQty":2,"names":"a/b/c","priced":2},"scoreTotal":6}}`
+	require.JSONEq(t, expected, string(out))
+
+	gjsonOut := gjson.Get(data, transfers.GjsonPath()).String()
+	require.JSONEq(t, expected, gjsonOut)
+
+	out, err = pathtransfer.Parse("items.#.price|sum:total\nitems.#.price|max:max").Apply([]byte(`{"items":[]}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"total":0}`, string(out))
+
+	_, err = pathtransfer.Parse("items.#.name|sum:total").Apply([]byte(data))
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PIPE_INPUT)
+
+	t.Run("quoted args", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+items.#.name|join(", "):Api.names
+items.#.name|join(" | "):Api.pipeNames
+		`)
+		require.Equal(t, []string{", "}, transfers[0].Pipes[0].Args)
+		require.Equal(t, "items.#.name|join(\", \"):Api.names\nitems.#.name|join(\" | \"):Api.pipeNames\n", transfers.String())
+		require.Equal(t, transfers.String(), pathtransfer.Parse(transfers.String()).String())
+		expected := `{"Api":{"names":"a, b, c","pipeNames":"a | b | c"}}`
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		require.JSONEq(t, expected, string(out))
+		require.JSONEq(t, expected, gjson.Get(data, transfers.GjsonPath()).String())
+	})
+}
@@ -0,0 +1,197 @@
+package pathtransfer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/pkg/errors"
+)
+
+var (
+	ERROR_SCRIPT_EXECUTOR_FUNC_CONFLICT = errors.New("script func name conflict")
+	ERROR_SCRIPT_EXECUTOR_OUTPUT        = errors.New("script func output invalid")
+)
+
+const (
+	ScriptExecutor_Var_Func   = "__func"
+	ScriptExecutor_Var_Input  = "__input"
+	ScriptExecutor_Var_Output = "__output"
+)
+
+// TengoExecutor 使用内嵌的 tengo 解释器执行转换函数,函数由脚本定义,无需重新编译服务
+// 函数的入参、出参定义来自转换关系中的 func.*.input/output,Call 可以直接作为 CallTransferFunc 的 closure
+type TengoExecutor struct {
+	mu        sync.Mutex
+	transfers Transfers
+	sources   []string
+	funcs     map[string]string // package.FuncName => tengo 函数
+	modules   []string
+	compiled  *tengo.Compiled
+}
+
+// TengoExecutor_Default_Modules 脚本可以导入的标准库模块,不包含 os
+var TengoExecutor_Default_Modules = []string{"base64", "enum", "fmt", "hex", "json", "math", "rand", "text", "times"}
+
+// NewTengoExecutor transfers 为函数的转换关系,用于生成入参、出参的适配代码
+func NewTengoExecutor(transfers Transfers) (executor *TengoExecutor) {
+	return &TengoExecutor{
+		transfers: transfers,
+		funcs:     make(map[string]string),
+		modules:   TengoExecutor_Default_Modules,
+	}
+}
+
+// AddSource 增加公共代码,如多个函数共用的辅助函数、模块导入,放在所有函数定义之前
+func (e *TengoExecutor) AddSource(source string) *TengoExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sources = append(e.sources, source)
+	e.compiled = nil
+	return e
+}
+
+// AddFunc 增加函数,funcName 格式为 [package.]FuncName;fn 为 tengo 函数(以 func 开头),或者函数体,此时入参名称取转换关系中的入参(ScriptVarName)
+// 多个出参时函数返回数组,返回 error 值时作为函数错误
+func (e *TengoExecutor) AddFunc(funcName string, fn string) *TengoExecutor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.funcs[strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func)] = fn
+	e.compiled = nil
+	return e
+}
+
+// Script 生成完整的脚本:公共代码、函数定义、调用适配代码,按 __func 分发调用
+func (e *TengoExecutor) Script() (script string, err error) {
+	callFuncs, err := e.transfers.CallFuncs()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(e.funcs))
+	for name := range e.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	selected := make(CallFuncs, 0)
+	funcNames := make(map[string]string)
+	var w strings.Builder
+	for _, source := range e.sources {
+		w.WriteString(source)
+		w.WriteString("\n")
+	}
+	for _, name := range names {
+		var callFunc *CallFunc
+		for i := range callFuncs {
+			if callFuncs[i].Name() == name {
+				callFunc = &callFuncs[i]
+				break
+			}
+		}
+		if callFunc == nil {
+			err = errors.WithMessagef(ERROR_TRANSFER_FUNC_NAME_NOT_FOUND, "func %s not found in transfers", name)
+			return "", err
+		}
+		if exists, ok := funcNames[callFunc.FuncName]; ok { // 适配代码以函数名命名,不同包的同名函数会冲突
+			err = errors.WithMessagef(ERROR_SCRIPT_EXECUTOR_FUNC_CONFLICT, "%s,%s", exists, name)
+			return "", err
+		}
+		funcNames[callFunc.FuncName] = name
+		fn := strings.TrimSpace(e.funcs[name])
+		if !strings.HasPrefix(fn, "func") {
+			fn = fmt.Sprintf("func(%s) {\n%s\n}", callFunc.Input.Uniq().ScriptVarNames("tengo"), fn)
+		}
+		w.WriteString(fmt.Sprintf("%s := %s\n", callFunc.FuncName, fn))
+		selected = append(selected, *callFunc)
+	}
+	if len(selected) > 0 {
+		adapter, err := selected.Script("tengo")
+		if err != nil {
+			return "", err
+		}
+		w.WriteString(adapter)
+		w.WriteString("\n")
+	}
+	calls := make([]string, 0, len(selected))
+	for _, cf := range selected {
+		calls = append(calls, fmt.Sprintf("%q: Call%s", cf.Name(), cf.FuncName))
+	}
+	w.WriteString(fmt.Sprintf("__calls := {%s}\n", strings.Join(calls, ", ")))
+	w.WriteString(fmt.Sprintf("%s := is_callable(__calls[%s]) ? __calls[%s](%s) : undefined\n",
+		ScriptExecutor_Var_Output, ScriptExecutor_Var_Func, ScriptExecutor_Var_Func, ScriptExecutor_Var_Input))
+	return w.String(), nil
+}
+
+// Compile 编译脚本,Call 时自动编译,提前调用可以尽早发现脚本错误
+func (e *TengoExecutor) Compile() (err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.compile()
+	return err
+}
+
+func (e *TengoExecutor) compile() (compiled *tengo.Compiled, err error) {
+	if e.compiled != nil {
+		return e.compiled, nil
+	}
+	script, err := e.Script()
+	if err != nil {
+		return nil, err
+	}
+	s := tengo.NewScript([]byte(script))
+	s.SetImports(stdlib.GetModuleMap(e.modules...))
+	for _, name := range []string{ScriptExecutor_Var_Func, ScriptExecutor_Var_Input} {
+		if err = s.Add(name, ""); err != nil {
+			return nil, err
+		}
+	}
+	e.compiled, err = s.Compile()
+	if err != nil {
+		err = errors.WithMessagef(err, "compile tengo script:\n%s", script)
+		return nil, err
+	}
+	return e.compiled, nil
+}
+
+// Call 执行函数,签名和 CallTransferFunc 的 closure 一致
+func (e *TengoExecutor) Call(funcName string, input []byte) (out []byte, err error) {
+	return e.CallContext(context.Background(), funcName, input)
+}
+
+// CallContext 同 Call,ctx 用于控制脚本执行超时
+func (e *TengoExecutor) CallContext(ctx context.Context, funcName string, input []byte) (out []byte, err error) {
+	funcName = strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func)
+	e.mu.Lock()
+	_, ok := e.funcs[funcName]
+	compiled, err := e.compile()
+	e.mu.Unlock()
+	if !ok {
+		err = errors.WithMessagef(ERROR_TRANSFER_FUNC_NAME_NOT_FOUND, "func:%s", funcName)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	compiled = compiled.Clone() // 每次执行使用独立的全局变量,支持并发
+	if err = compiled.Set(ScriptExecutor_Var_Func, funcName); err != nil {
+		return nil, err
+	}
+	if err = compiled.Set(ScriptExecutor_Var_Input, string(input)); err != nil {
+		return nil, err
+	}
+	if err = compiled.RunContext(ctx); err != nil {
+		return nil, err
+	}
+	output := compiled.Get(ScriptExecutor_Var_Output)
+	if err = output.Error(); err != nil {
+		return nil, err
+	}
+	if output.ValueType() != "string" {
+		err = errors.WithMessagef(ERROR_SCRIPT_EXECUTOR_OUTPUT, "func %s return %s", funcName, output.ValueType())
+		return nil, err
+	}
+	return []byte(output.String()), nil
+}
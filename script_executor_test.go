@@ -0,0 +1,57 @@
+package pathtransfer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestTengoExecutor(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.vocabulary.SetLimit.input.index@int:data.pagination.index
+func.vocabulary.SetLimit.input.size@int:data.pagination.size
+func.vocabulary.SetLimit.output.offset@int:data.limit.offset
+func.vocabulary.SetLimit.output.size@int:data.limit.size
+func.vocabulary.TrimName.input.name@string:user.name
+func.vocabulary.TrimName.output.name@string:data.userName
+func.vocabulary.Loop.input.n@int:data.n
+func.vocabulary.Loop.output.n@int:data.n
+	`)
+	executor := pathtransfer.NewTengoExecutor(transfers).
+		AddSource(`text := import("text")`).
+		AddFunc("vocabulary.SetLimit", `
+if index < 1 {
+	return error("index must be greater than 0")
+}
+return [(index-1)*size, size]`).
+		AddFunc("vocabulary.TrimName", `func(name) { return text.trim_space(name) }`).
+		AddFunc("vocabulary.Loop", `for { n++ }`)
+	require.NoError(t, executor.Compile())
+
+	out, err := executor.Call("vocabulary.SetLimit", []byte(`{"index":"3","size":10}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"offset":20,"size":10}`, string(out))
+
+	out, err = pathtransfer.CallTransferFunc(transfers.GetByNamespace("func.vocabulary.TrimName"), []byte(`{"user":{"name":" zhang "}}`), executor.Call)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"user":{"name":" zhang "},"data":{"userName":"zhang"}}`, string(out))
+
+	_, err = executor.Call("vocabulary.SetLimit", []byte(`{"index":0,"size":10}`))
+	require.ErrorContains(t, err, "index must be greater than 0")
+
+	_, err = executor.Call("vocabulary.Missing", []byte(`{}`))
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_NAME_NOT_FOUND)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = executor.CallContext(ctx, "vocabulary.Loop", []byte(`{"n":1}`))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = pathtransfer.NewTengoExecutor(transfers).AddFunc("vocabulary.SetLimit", `return index +`).Compile()
+	require.Error(t, err)
+	err = pathtransfer.NewTengoExecutor(transfers).AddFunc("vocabulary.Unknown", `return 1`).Compile()
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_NAME_NOT_FOUND)
+}
@@ -0,0 +1,106 @@
+package pathtransfer
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+)
+
+const (
+	TraceReason_Missing   = "missing"   // 来源不存在(含条件不满足),目标不输出
+	TraceReason_Null      = "null"      // 来源为null,目标输出null
+	TraceReason_TypeError = "typeError" // 类型转换失败,目标输出原始值
+	TraceReason_Error     = "error"     // 转换出错(表达式、后置处理出错等),整体转换失败
+)
+
+// TraceItem 转换追踪记录
+type TraceItem struct {
+	Dst      Path   `json:"dst"`
+	Transfer string `json:"transfer"`
+	Src      Path   `json:"src"`              // 实际取值的来源路径,存在备选来源时为第一个有值的路径,计算转换、来源不存在时为空
+	Raw      string `json:"raw,omitempty"`    // 来源原始值
+	Value    string `json:"value,omitempty"`  // 类型转换后的值
+	Reason   string `json:"reason,omitempty"` // 未正常转换的原因 TraceReason_XXX
+	Error    string `json:"error,omitempty"`  // 类型转换失败、转换出错的错误信息
+}
+
+// newTraceItem 根据取值结果生成追踪记录
+func newTraceItem(transfer Transfer, src Path, rv resolvedValue) (traceItem TraceItem) {
+	traceItem = TraceItem{
+		Dst:      transfer.Dst.Path,
+		Transfer: transfer.String(),
+		Src:      src,
+	}
+	if !rv.exists {
+		traceItem.Reason = TraceReason_Missing
+		return traceItem
+	}
+	traceItem.Raw = rv.json("")
+	value, err := rv.jsonE(transfer.dstType())
+	err = transfer.withTypeConversionError(err)
+	traceItem.Value = value
+	switch {
+	case err != nil:
+		traceItem.Reason, traceItem.Error = TraceReason_TypeError, err.Error()
+	case !rv.present():
+		traceItem.Reason = TraceReason_Null
+	}
+	return traceItem
+}
+
+func (ti TraceItem) String() string {
+	s := fmt.Sprintf("%s <= %s (%s)", ti.Dst, ti.Src, ti.Transfer)
+	if ti.Reason != "" {
+		s = fmt.Sprintf("%s %s", s, ti.Reason)
+	}
+	return s
+}
+
+// Trace 转换追踪,和转换关系一一对应
+type Trace []TraceItem
+
+// GetByDst 获取目标路径的追踪记录
+func (trace Trace) GetByDst(dst Path) (traceItem *TraceItem, ok bool) {
+	for _, item := range trace {
+		if item.Dst.EqualFold(dst) {
+			return &item, true
+		}
+	}
+	return nil, false
+}
+
+// Misses 获取未正常转换的记录
+func (trace Trace) Misses() (misses Trace) {
+	misses = make(Trace, 0)
+	for _, item := range trace {
+		if item.Reason != "" {
+			misses = append(misses, item)
+		}
+	}
+	return misses
+}
+
+func (trace Trace) String() string {
+	var w bytes.Buffer
+	for _, item := range trace {
+		w.WriteString(item.String())
+		w.WriteString("\n")
+	}
+	return w.String()
+}
+
+// Table 格式化为表格,explain 命令使用
+func (trace Trace) Table() string {
+	var w bytes.Buffer
+	tw := tabwriter.NewWriter(&w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DST\tTRANSFER\tSRC\tRAW\tVALUE\tREASON")
+	for _, item := range trace {
+		reason := item.Reason
+		if item.Error != "" {
+			reason = fmt.Sprintf("%s: %s", reason, item.Error)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Dst, item.Transfer, item.Src, item.Raw, item.Value, reason)
+	}
+	tw.Flush()
+	return w.String()
+}
@@ -0,0 +1,49 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestApplyWithTrace(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+user.name:Api.name
+user.age:Api.age@int
+user.nick:Api.nick
+user.email:Api.email
+user.level:Api.level@int when user.vip
+items.#.price:Api.items.#.price@number
+	`)
+	data := `{"user":{"name":"张三","age":"abc","email":null,"level":"3"},"items":[{"price":"1.5"},{}]}`
+	out, trace, err := transfers.ApplyWithTrace([]byte(data))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Api":{"name":"张三","age":"abc","email":null,"items":[{"price":1.5}]}}`, string(out))
+	require.Len(t, trace, len(transfers))
+
+	expected := pathtransfer.Trace{
+		{Dst: "Api.name", Transfer: "user.name:Api.name", Src: "user.name", Raw: `"张三"`, Value: `"张三"`},
+		{Dst: "Api.age", Transfer: "user.age:Api.age@int", Src: "user.age", Raw: `"abc"`, Value: `"abc"`, Reason: pathtransfer.TraceReason_TypeError, Error: `type conversion failed: path Api.age value "abc" to int (transfer user.age:Api.age@int)`},
+		{Dst: "Api.nick", Transfer: "user.nick:Api.nick", Reason: pathtransfer.TraceReason_Missing},
+		{Dst: "Api.email", Transfer: "user.email:Api.email", Src: "user.email", Raw: "null", Value: "null", Reason: pathtransfer.TraceReason_Null},
+		{Dst: "Api.level", Transfer: "user.level:Api.level@int when user.vip", Reason: pathtransfer.TraceReason_Missing},
+		{Dst: "Api.items.#.price", Transfer: "items.#.price:Api.items.#.price@number", Src: "items.#.price", Raw: `["1.5",null]`, Value: `[1.5,null]`},
+	}
+	require.Equal(t, expected, trace)
+	require.Len(t, trace.Misses(), 4)
+	require.Contains(t, trace.Table(), "typeError")
+}
+
+func TestApplyWithTraceError(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+items.#.name|sum:Api.total
+items.#.name:Api.names
+	`)
+	_, trace, err := transfers.ApplyWithTrace([]byte(`{"items":[{"name":"a"}]}`))
+	require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PIPE_INPUT)
+	require.Len(t, trace, 2)
+	require.Equal(t, pathtransfer.TraceReason_Error, trace[0].Reason)
+	require.Contains(t, trace[0].Error, "number required")
+	require.Empty(t, trace[1].Reason)
+}
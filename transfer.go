@@ -11,7 +11,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cast"
 	"github.com/suifengpiao14/funcs"
-	"github.com/suifengpiao14/gjsonmodifier"
 	"github.com/tidwall/gjson"
 )
 
@@ -85,6 +84,7 @@ func (tu TransferUnit) FuncParameter() (funcParameter *FuncParameter, err error)
 	}
 	if !funcPath.HasNamespace(Transfer_Top_Namespace_Func) {
 		err = errors.WithMessagef(ERROR_TRANSFER_PATH_NAMESPACE_NOT_FUNC, "func path require prefix:%s,got:%s", Transfer_Top_Namespace_Func, funcPath)
+		err = &PathSyntaxError{Path: funcPath, Err: err}
 		return nil, err
 	}
 	if funcPath.IsIn() {
@@ -99,47 +99,126 @@ func (tu TransferUnit) FuncParameter() (funcParameter *FuncParameter, err error)
 			Transfer_Direction_output,
 			funcPath,
 		)
+		err = &PathSyntaxError{Path: funcPath, Err: err}
 		return nil, err
 	}
 
 	funcPath = funcPath.TrimNamespace(Transfer_Top_Namespace_Func)
 	funcName, arg := funcPath.SplitByIO()
 
-	funcParameter.FuncName, funcParameter.Name = funcName, arg
+	funcParameter.FuncName = funcName
 	lastDot := strings.LastIndex(funcParameter.FuncName, ".")
 	if lastDot > -1 {
 		funcParameter.Package, funcParameter.FuncName = funcParameter.FuncName[:lastDot], funcParameter.FuncName[lastDot+1:]
 	}
-	firstDot := strings.Index(funcParameter.Name, ".")
-	if firstDot > -1 {
-		funcParameter.Name = funcParameter.Name[:firstDot] // 保留第一层
-		funcParameter.Type = "object"
-	}
-	if strings.HasSuffix(funcParameter.Name, "#") {
-		funcParameter.Name = strings.TrimSuffix(funcParameter.Name, "#") // 删除结尾的#
-		funcParameter.Type = "array"
-	}
-	funcParameter.Path = JoinPath(Transfer_Top_Namespace_Func, funcName, funcParameter.Direction, funcParameter.Name) // 剔除name后面的部分，对于对象和原始的funcpath有区别
+	// 只保留第一层作为参数,如 pagination.index 参数名为 pagination,类型为 object,index 记录在 Fields 中;结尾的 # 表示数组
+	field := newFuncField(JoinPath(Transfer_Top_Namespace_Func, funcName, funcParameter.Direction), arg, tu.Type)
+	funcParameter.Name, funcParameter.Path, funcParameter.Type = field.Name, field.Path, field.Type
+	funcParameter.Fields, funcParameter.ItemType = field.Fields, field.ItemType
 	return funcParameter, nil
 }
 
+// newFuncField 按局部路径生成参数(字段)定义,嵌套的对象、数组递归生成 Fields
+func newFuncField(parent Path, localPath string, typ string) (field FuncParameter) {
+	name, rest, hasRest := strings.Cut(localPath, ".")
+	isArray := strings.HasSuffix(name, "#")
+	name = strings.TrimSuffix(name, "#") // 删除结尾的#
+	field = FuncParameter{Name: name, Path: JoinPath(parent.String(), name), Type: typ}
+	switch {
+	case isArray && hasRest:
+		field.Type = "array"
+		field.Fields = FuncParameters{newFuncField(JoinPath(field.Path.String(), "#"), rest, typ)}
+	case isArray:
+		field.Type, field.ItemType = "array", typ
+	case hasRest:
+		field.Type = "object"
+		field.Fields = FuncParameters{newFuncField(field.Path, rest, typ)}
+	}
+	return field
+}
+
 const (
 	TransferUnit_Type_Int    = "int"
 	TransferUnit_Type_String = "string"
+	TransferUnit_Type_Number = "number"
 )
 
 type Transfer struct {
-	Src TransferUnit `json:"src"`
-	Dst TransferUnit `json:"dst"`
+	Src  TransferUnit `json:"src"`
+	Dst  TransferUnit `json:"dst"`
+	Expr string       `json:"expr,omitempty"` // 计算表达式,不为空时使用表达式结果作为来源值(Src 无效),转换协议中以 = 开头
+	When string       `json:"when,omitempty"` // 条件表达式,基于来源数据计算,为真时才转换,转换协议中以 when 开头放在最后
+	// 备选来源路径,Src 不存在或为null时依次尝试,第一个存在且不为null的值生效,转换协议中以 ?? 分隔,如 user_name??userName??name:Api.name
+	Fallback   []Path        `json:"fallback,omitempty"`
+	Pipes      TransferPipes `json:"pipes,omitempty"`      // 来源值后置处理(数组展开、分组等),转换协议中以 | 跟在来源后面
+	NullPolicy NullPolicy    `json:"nullPolicy,omitempty"` // null、空字符串、空数组的处理策略 NullPolicy_XXX,为空时使用全局策略
 }
 
 func (t Transfer) String() (s string) {
 	var w bytes.Buffer
-	w.WriteString(t.Src.String())
+	if t.Expr != "" {
+		w.WriteString(fmt.Sprintf("%s%s", Transfer_Expr_Prefix, t.Expr))
+	} else {
+		w.WriteString(t.Src.String())
+		for _, path := range t.Fallback {
+			w.WriteString(fmt.Sprintf("%s%s", Transfer_Coalesce_Separator, path))
+		}
+	}
+	w.WriteString(t.Pipes.String())
 	w.WriteString(":")
 	w.WriteString(t.Dst.String())
+	if t.NullPolicy != "" {
+		w.WriteString(fmt.Sprintf("%s%s", Transfer_Null_Keyword, t.NullPolicy))
+	}
+	if t.When != "" {
+		w.WriteString(fmt.Sprintf("%s%s", Transfer_When_Keyword, t.When))
+	}
 	return w.String()
 }
+
+// valueExpr 将来源值、条件统一为一个表达式,如 src:dst when cond 转换为 if(cond,`src`,null),a??b:dst 转换为 coalesce(`a`,`b`)
+func (t Transfer) valueExpr() (expr string) {
+	expr = t.Expr
+	if expr == "" {
+		path := t.Src.Path
+		if path == "" {
+			path = "@this"
+		}
+		expr = fmt.Sprintf("`%s`", path)
+	}
+	if t.Expr == "" && len(t.Fallback) > 0 {
+		args := []string{expr}
+		for _, path := range t.Fallback {
+			args = append(args, fmt.Sprintf("`%s`", path))
+		}
+		expr = fmt.Sprintf("coalesce(%s)", strings.Join(args, ","))
+	}
+	if t.When != "" {
+		expr = fmt.Sprintf("if(%s,%s,null)", t.When, expr)
+	}
+	return expr
+}
+
+// dstType 目标类型,没有指定时使用后置处理结果的默认类型
+func (t Transfer) dstType() (typ string) {
+	if t.Dst.Type != "" {
+		return t.Dst.Type
+	}
+	return t.Pipes.Type()
+}
+
+// SrcPaths 来源路径(含备选来源路径),计算转换返回表达式引用的所有路径
+func (t Transfer) SrcPaths() (paths []Path) {
+	if t.Expr == "" {
+		return append([]Path{t.Src.Path}, t.Fallback...)
+	}
+	expr, err := ParseExpr(t.Expr)
+	if err != nil {
+		return []Path{}
+	}
+	return expr.Paths()
+}
+
 func (t Transfer) IsIn() bool {
 	return isIn(t.Src.Path)
 }
@@ -158,6 +237,28 @@ func NewTransfer() (transfer Transfers) {
 	return Transfers{}
 }
 
+const (
+	Transfer_Expr_Prefix        = "="      // 转换协议中计算表达式前缀
+	Transfer_When_Keyword       = " when " // 转换协议中条件子句关键字
+	Transfer_Coalesce_Separator = "??"     // 转换协议中备选来源路径分隔符
+	Transfer_Null_Keyword       = " null=" // 转换协议中null处理策略关键字
+)
+
+const (
+	Path_Wildcard = "*"    // 路径中对象的动态key,对象的每个key对应的值按顺序展开成数组,和 # 对应
+	Path_Key      = "@key" // 跟在 * 后面表示对象的key(跟在 # 后面表示数组下标),目标路径 xxx.*.@key 为 * 提供key
+)
+
+// hasWildcard 判断路径中是否有动态key *
+func hasWildcard(path Path) bool {
+	for _, seg := range splitPath(path) {
+		if seg == Path_Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	Transfer_Direction_input  = ".input"  //函数入参
 	Transfer_Direction_output = ".output" //函数出参
@@ -220,12 +321,17 @@ func (transfer *Transfers) AddReplace(transferItems ...Transfer) {
 	}
 }
 
+// Reverse 交换来源和目标;计算转换、后置处理、条件转换不可逆,跳过;备选来源只还原到主来源路径,null策略保持不变
 func (transfer Transfers) Reverse() (reversedTransfer Transfers) {
 	reversedTransfer = Transfers{}
 	for _, item := range transfer {
+		if item.Expr != "" || len(item.Pipes) > 0 || item.When != "" { // 计算转换、后置处理、条件转换不可逆
+			continue
+		}
 		refersedItem := Transfer{
-			Src: item.Dst,
-			Dst: item.Src,
+			Src:        item.Dst,
+			Dst:        item.Src,
+			NullPolicy: item.NullPolicy,
 		}
 		reversedTransfer = append(reversedTransfer, refersedItem)
 	}
@@ -236,6 +342,18 @@ func (transfer Transfers) Reverse() (reversedTransfer Transfers) {
 func (t Transfers) appendTypeToPath() (newT Transfers) {
 	newT = make(Transfers, 0)
 	for _, transfer := range t {
+		if len(transfer.Pipes) > 0 || hasArrayModifier(transfer.Src.Path) || hasWildcard(transfer.Src.Path) || transfer.NullPolicy != "" { // 后置处理、数组修改、动态key、null策略使用 @transfer 修改器,由转换引擎计算
+			arg, _ := json.Marshal(map[string]string{"transfer": transfer.String()})
+			transfer.Src.Path = Path(fmt.Sprintf("@transfer:%s", string(arg)))
+			newT = append(newT, transfer)
+			continue
+		}
+		if transfer.Expr != "" || transfer.When != "" || len(transfer.Fallback) > 0 { // 计算转换、条件转换、备选来源使用 @expr 修改器,类型转换在修改器内完成
+			arg, _ := json.Marshal(map[string]string{"expr": transfer.valueExpr(), "type": transfer.Dst.Type})
+			transfer.Src.Path = Path(fmt.Sprintf("@expr:%s", string(arg)))
+			newT = append(newT, transfer)
+			continue
+		}
 		if transfer.Src.Path == "" { // 路径为空,使用当前数据(如 userTotal.output  去除命名空间后为空,实际数据库返回也是一个整形,没有key)
 			transfer.Src.Path = Path("@this")
 		}
@@ -307,7 +425,7 @@ func (ts Transfers) GetSrcNamespace(delim string) (namespaces []string) {
 	for namespace := range m {
 		namespaces = append(namespaces, namespace)
 	}
-
+	sort.Strings(namespaces) // 保证顺序稳定
 	return namespaces
 
 }
@@ -353,7 +471,7 @@ func JoinPath(paths ...string) (newPath Path) {
 }
 
 func (t Transfers) GjsonPath() (gjsonPath string) {
-	newT := t.appendTypeToPath()
+	newT := t.groupWildcardDst().appendTypeToPath()
 	m := &transfersModel{
 		keys: make([]string, 0),
 		m:    make(map[string]any),
@@ -412,6 +530,41 @@ func (t Transfers) GjsonPath() (gjsonPath string) {
 	return gjsonPath
 }
 
+// groupWildcardDst 目标路径包含 * 的转换按 * 之前的路径合并,使用 @apply 修改器由转换引擎生成对象
+func (t Transfers) groupWildcardDst() (newT Transfers) {
+	newT = make(Transfers, 0)
+	groups := make(map[Path]Transfers)
+	for _, transfer := range t {
+		segments := splitPath(normalizeDstPath(transfer.Dst.Path))
+		index := -1
+		for i, seg := range segments {
+			if seg == Path_Wildcard {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			newT = append(newT, transfer)
+			continue
+		}
+		prefix := Path(strings.Join(segments[:index], "."))
+		if _, ok := groups[prefix]; !ok {
+			newT = append(newT, Transfer{Dst: TransferUnit{Path: prefix}})
+		}
+		transfer.Dst.Path = Path(strings.Join(segments[index:], "."))
+		groups[prefix] = append(groups[prefix], transfer)
+	}
+	for i, transfer := range newT {
+		group, ok := groups[transfer.Dst.Path]
+		if !ok || transfer.Src.Path != "" {
+			continue
+		}
+		arg, _ := json.Marshal(map[string]string{"transfers": group.String()})
+		newT[i].Src.Path = Path(fmt.Sprintf("@apply:%s", string(arg)))
+	}
+	return newT
+}
+
 // 生成路径
 func (t Transfers) recursionWrite(m *transfersModel, parentIsArray bool, depth int) (w bytes.Buffer, childrenIsArray bool) {
 	writeComma := false
@@ -511,7 +664,34 @@ func PathModifyFnTrimPrefixFn(prefix Path) (pathModifyFn PathModifyFn) {
 	}
 }
 
-// ModifyPath 修改转换路径
+// PathModifyFnTrimKeyPrefixFn 生成剔除每一层key前缀的修改函数(如数据库字段 Fuser_id 剔除 F 前缀)
+// 只剔除匈牙利前缀:前缀后紧跟小写字母(Fuser_id => user_id),或者以 _ 分隔(F_name => name),FORM、F1 等key不修改;
+// Form 这类普通单词无法和前缀区分,需要时配合 PathScopeMatch 限定修改范围
+func PathModifyFnTrimKeyPrefixFn(prefix string) (pathModifyFn PathModifyFn) {
+	return func(path Path) (newPath Path) {
+		return modifyPathSegments(path, func(key string) (newKey string) {
+			return trimHungarianPrefix(key, prefix)
+		})
+	}
+}
+
+// trimHungarianPrefix 剔除key的匈牙利前缀,不是匈牙利前缀时原样返回
+func trimHungarianPrefix(key string, prefix string) (newKey string) {
+	if prefix == "" || !strings.HasPrefix(key, prefix) {
+		return key
+	}
+	rest := key[len(prefix):]
+	switch {
+	case len(rest) > 1 && rest[0] == '_':
+		return rest[1:]
+	case rest != "" && rest[0] >= 'a' && rest[0] <= 'z':
+		return rest
+	}
+	return key
+}
+
+// ModifyDstPath 修改目标路径,不检测冲突(不同原始路径修改后相同时,写入数据后者覆盖前者),保持原有签名兼容已有调用;
+// 修改函数可能产生冲突(如剔除前缀、格式转换)时使用 ModifyDstPathWithPolicy
 func (t Transfers) ModifyDstPath(dstPathModifyFns ...PathModifyFn) (nt Transfers) {
 	nt = make(Transfers, 0)
 	for _, l := range t {
@@ -531,6 +711,8 @@ func (t Transfers) ModifyDstPath(dstPathModifyFns ...PathModifyFn) (nt Transfers
 	}
 	return nt
 }
+
+// ModifySrcPath 修改来源路径,不检测冲突,同 ModifyDstPath;需要检测冲突时使用 ModifySrcPathWithPolicy
 func (t Transfers) ModifySrcPath(srcPathModifyFns ...PathModifyFn) (nt Transfers) {
 	nt = make(Transfers, 0)
 	for _, l := range t {
@@ -550,8 +732,116 @@ func (t Transfers) ModifySrcPath(srcPathModifyFns ...PathModifyFn) (nt Transfers
 	return nt
 }
 
-// GetCallFnScript 从transfer中获取调用函数的动态脚本
-func (ts Transfers) GetCallFnScript(language string) (callScript string, err error) {
+const (
+	PathCollisionPolicy_Fail      = "fail"      // 存在冲突时返回错误(默认,策略为空时使用)
+	PathCollisionPolicy_KeepFirst = "keepFirst" // 保留第一个,丢弃后续冲突的转换
+	PathCollisionPolicy_Suffix    = "suffix"    // 后续冲突的路径最后一层key增加 _2、_3 等后缀
+)
+
+// PathCollision 不同的原始路径修改后变成同一个路径
+type PathCollision struct {
+	Path         Path `json:"path"`         // 修改后的路径
+	FirstOrigin  Path `json:"firstOrigin"`  // 先出现的原始路径
+	SecondOrigin Path `json:"secondOrigin"` // 后出现的原始路径
+	NewPath      Path `json:"newPath"`      // PathCollisionPolicy_Suffix 策略下后者实际使用的路径
+}
+
+func (pc PathCollision) String() string {
+	return fmt.Sprintf("%s and %s both modify to %s", pc.FirstOrigin, pc.SecondOrigin, pc.Path)
+}
+
+type PathCollisions []PathCollision
+
+func (pcs PathCollisions) String() string {
+	arr := make([]string, 0)
+	for _, pc := range pcs {
+		arr = append(arr, pc.String())
+	}
+	return strings.Join(arr, ";")
+}
+
+// ModifyDstPathWithPolicy 修改目标路径,检测不同原始路径修改后冲突的情况,按策略处理并返回所有冲突
+func (t Transfers) ModifyDstPathWithPolicy(policy string, dstPathModifyFns ...PathModifyFn) (nt Transfers, collisions PathCollisions, err error) {
+	return t.modifyPathWithPolicy(false, policy, dstPathModifyFns...)
+}
+
+// ModifySrcPathWithPolicy 修改来源路径,检测不同原始路径修改后冲突的情况,按策略处理并返回所有冲突
+func (t Transfers) ModifySrcPathWithPolicy(policy string, srcPathModifyFns ...PathModifyFn) (nt Transfers, collisions PathCollisions, err error) {
+	return t.modifyPathWithPolicy(true, policy, srcPathModifyFns...)
+}
+
+func (t Transfers) modifyPathWithPolicy(isSrc bool, policy string, pathModifyFns ...PathModifyFn) (nt Transfers, collisions PathCollisions, err error) {
+	switch policy {
+	case "":
+		policy = PathCollisionPolicy_Fail
+	case PathCollisionPolicy_Fail, PathCollisionPolicy_KeepFirst, PathCollisionPolicy_Suffix:
+	default:
+		err = errors.WithMessagef(ERROR_TRANSFER_PATH_COLLISION_POLICY, "got:%s", policy)
+		return nil, nil, err
+	}
+	nt = make(Transfers, 0)
+	collisions = make(PathCollisions, 0)
+	origins := make(map[Path]Path) // 修改后的路径 => 原始路径
+	for _, l := range t {
+		unit := &l.Dst
+		if isSrc {
+			unit = &l.Src
+		}
+		origin := unit.Path
+		for _, fn := range pathModifyFns {
+			if fn != nil {
+				unit.Path = fn(unit.Path)
+			}
+		}
+		firstOrigin, ok := origins[unit.Path]
+		if !ok || firstOrigin == origin {
+			origins[unit.Path] = origin
+			nt.AddReplace(l)
+			continue
+		}
+		collision := PathCollision{
+			Path:         unit.Path,
+			FirstOrigin:  firstOrigin,
+			SecondOrigin: origin,
+		}
+		switch policy {
+		case PathCollisionPolicy_KeepFirst:
+		case PathCollisionPolicy_Suffix:
+			for i := 2; ; i++ {
+				newPath := pathAddSuffix(unit.Path, fmt.Sprintf("_%d", i))
+				if existsOrigin, exists := origins[newPath]; !exists || existsOrigin == origin {
+					collision.NewPath = newPath
+					break
+				}
+			}
+			unit.Path = collision.NewPath
+			origins[unit.Path] = origin
+			nt.AddReplace(l)
+		}
+		collisions = append(collisions, collision)
+	}
+	if policy == PathCollisionPolicy_Fail && len(collisions) > 0 {
+		err = errors.WithMessage(ERROR_TRANSFER_KEY_COLLISION, collisions.String())
+		return nil, collisions, err
+	}
+	return nt, collisions, nil
+}
+
+// pathAddSuffix 路径最后一层非数组key增加后缀
+func pathAddSuffix(path Path, suffix string) (newPath Path) {
+	arr := strings.Split(path.String(), ".")
+	for i := len(arr) - 1; i >= 0; i-- {
+		if arr[i] != "#" {
+			arr[i] = fmt.Sprintf("%s%s", arr[i], suffix)
+			break
+		}
+	}
+	newPath = Path(strings.Join(arr, "."))
+	return newPath
+}
+
+// CallFuncs 从transfer中获取调用的函数
+func (ts Transfers) CallFuncs() (callFuncs CallFuncs, err error) {
 	funcParameters := make(FuncParameters, 0)
 	for _, t := range ts {
 		funcParameter, err := t.Src.FuncParameter()
@@ -560,13 +850,20 @@ func (ts Transfers) GetCallFnScript(language string) (callScript string, err err
 			continue
 		}
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		funcParameters.AddReplace(*funcParameter)
 
 	}
+	return funcParameters.CallFuncs(), nil
+}
 
-	callFuncs := funcParameters.CallFuncs()
+// GetCallFnScript 从transfer中获取调用函数的动态脚本
+func (ts Transfers) GetCallFnScript(language string) (callScript string, err error) {
+	callFuncs, err := ts.CallFuncs()
+	if err != nil {
+		return "", err
+	}
 	callScript, err = callFuncs.Script(language)
 	if err != nil {
 		return "", err
@@ -625,6 +922,8 @@ func toGoTypeTransfer(rt reflect.Type, prefix Path) (lineschemaTransfer Transfer
 		lineschemaTransfer = toGoTypeTransfer(rt.Elem(), Path(fmt.Sprintf("%s.#", prefix)))
 	case reflect.Struct:
 		lineschemaTransfer = str2StructTransfer(rt, prefix)
+	case reflect.Map:
+		lineschemaTransfer = str2MapTransfer(rt, prefix)
 	case reflect.Int64, reflect.Float64, reflect.Int:
 		lineschemaTransfer = str2SimpleTypeTransfer("number", prefix)
 	case reflect.Bool:
@@ -642,6 +941,27 @@ func toGoTypeTransfer(rt reflect.Type, prefix Path) (lineschemaTransfer Transfer
 	return lineschemaTransfer
 }
 
+// str2MapTransfer map[string]T、map[int]T 使用动态key *,同时保留key(同 encoding/json,整数key在json中为字符串)
+func str2MapTransfer(rt reflect.Type, prefix Path) (lineschemaTransfer Transfers) {
+	if rt.Kind() != reflect.Map {
+		return nil
+	}
+	switch rt.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return nil
+	}
+	wildcard := JoinPath(prefix.String(), Path_Wildcard)
+	keyPath := JoinPath(wildcard.String(), Path_Key)
+	lineschemaTransfer = Transfers{
+		{Src: TransferUnit{Path: keyPath}, Dst: TransferUnit{Path: keyPath}},
+	}
+	lineschemaTransfer = append(lineschemaTransfer, toGoTypeTransfer(rt.Elem(), wildcard)...)
+	return lineschemaTransfer
+}
+
 func str2SimpleTypeTransfer(typ string, path Path) (lineschemaTransfer Transfers) {
 	if path == "" {
 		path = "@this"
@@ -695,6 +1015,10 @@ func str2StructTransfer(rt reflect.Type, pPrefix Path) (transfers Transfers) {
 			subTransfer := str2StructTransfer(fieldType, Path(subPrefix))
 			transfers.AddReplace(subTransfer...)
 			continue // 复合类型，只收集子值
+		case reflect.Map:
+			subPrefix := fmt.Sprintf("%s%s", prefix, tag)
+			transfers.AddReplace(str2MapTransfer(fieldType, Path(subPrefix))...)
+			continue
 		}
 		if tag == "" {
 			tag = field.Name // 根据json.Umarsh/Marsh 发现未写json tag时，默认使用列名称，此处兼容保持一致
@@ -716,26 +1040,126 @@ func str2StructTransfer(rt reflect.Type, pPrefix Path) (transfers Transfers) {
 	return transfers
 }
 
+var (
+	ERROR_TRANSFER_KEY_COLLISION         = errors.New("key collision")
+	ERROR_TRANSFER_PATH_COLLISION_POLICY = errors.New("unknown path collision policy")
+)
+
+const (
+	KeyCase_CamelCase = "camelCase" // 小驼峰
+	KeyCase_SnakeCase = "snakeCase" // 下划线
+	KeyCase_Lower     = "lower"     // 小写
+)
+
+// RebuildJsonOption 重建json的配置,处理顺序: DropKeys -> TrimKeyPrefix -> KeyCase -> PathModifyFns -> ModifyTransferFn
+type RebuildJsonOption struct {
+	KeyCase          string                                             // key 格式,取值 KeyCase_XXX,为空则不修改
+	TrimKeyPrefix    string                                             // 剔除每一层key的前缀,如数据库字段的匈牙利前缀 F
+	DropKeys         []Path                                             // 删除的路径(数组下标使用#表示),删除路径下的所有子节点
+	PathModifyFns    []PathModifyFn                                     // 自定义路径修改函数
+	ModifyTransferFn func(transfers Transfers) (newTransfers Transfers) // 自定义修改转换关系
+	PreserveUnknown  bool                                               // ModifyTransferFn 返回的转换关系中不包含的路径,保持原样输出,否则删除
+	ErrorOnCollision bool                                               // 不同的key转换后为同一个key时返回错误,否则后者覆盖前者
+}
+
+func (option RebuildJsonOption) isDropped(path Path) bool {
+	for _, dropKey := range option.DropKeys {
+		if path == dropKey || strings.HasPrefix(path.String(), fmt.Sprintf("%s.", dropKey)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (option RebuildJsonOption) pathModifyFns() (fns []PathModifyFn) {
+	fns = make([]PathModifyFn, 0)
+	if option.TrimKeyPrefix != "" {
+		fns = append(fns, PathModifyFnTrimKeyPrefixFn(option.TrimKeyPrefix))
+	}
+	if caseFn := PathModifyFnCaseFn(option.KeyCase); caseFn != nil {
+		fns = append(fns, caseFn)
+	}
+	fns = append(fns, option.PathModifyFns...)
+	return fns
+}
+
 // RebuildJson 重建json数据 修改json数据的key, 比如下划线修改为小驼峰
 func RebuildJson(s string, modifyTransferFn func(transfer Transfers) (newTransfer Transfers)) (newS string, err error) {
-	paths := gjsonmodifier.GetAllPath(s)
+	return RebuildJsonWithOption(s, RebuildJsonOption{ModifyTransferFn: modifyTransferFn})
+}
+
+// RebuildJsonWithOption 按配置重建json数据,转换关系的路径中数组下标统一为#,空对象、空数组、null 保持原样
+func RebuildJsonWithOption(s string, option RebuildJsonOption) (newS string, err error) {
+	if !gjson.Valid(s) {
+		err = errors.Errorf("RebuildJson invalid json:%s", s)
+		return "", err
+	}
+	leaves := getAllJsonLeaf(s)
 	transfers := make(Transfers, 0)
-	for _, pathStr := range paths {
-		path := Path(pathStr)
+	exists := make(map[Path]struct{})
+	for _, leaf := range leaves {
+		if _, ok := exists[leaf.Path]; ok {
+			continue
+		}
+		exists[leaf.Path] = struct{}{}
+		if option.isDropped(leaf.Path) {
+			continue
+		}
 		transfer := Transfer{
 			Src: TransferUnit{
-				Path: path,
+				Path: leaf.Path,
 			},
 			Dst: TransferUnit{
-				Path: path,
+				Path: leaf.Path,
 			},
 		}
+		for _, fn := range option.pathModifyFns() {
+			if fn != nil {
+				transfer.Dst.Path = fn(transfer.Dst.Path)
+			}
+		}
 		transfers = append(transfers, transfer)
 	}
-	if modifyTransferFn != nil {
-		transfers = modifyTransferFn(transfers)
+	if option.ModifyTransferFn != nil {
+		transfers = option.ModifyTransferFn(transfers)
+	}
+	srcMap := make(map[Path]Transfers)
+	dstMap := make(map[Path]Path)
+	for _, t := range transfers {
+		if srcPath, ok := dstMap[t.Dst.Path]; ok && srcPath != t.Src.Path && option.ErrorOnCollision {
+			err = errors.WithMessagef(ERROR_TRANSFER_KEY_COLLISION, "%s and %s both rebuild to %s", srcPath, t.Src.Path, t.Dst.Path)
+			return "", err
+		}
+		dstMap[t.Dst.Path] = t.Src.Path
+		srcMap[t.Src.Path] = append(srcMap[t.Src.Path], t)
+	}
+
+	var root any
+	switch {
+	case gjson.Parse(s).IsObject():
+		root = newJsonObject()
+	case gjson.Parse(s).IsArray():
+		root = &jsonArray{}
+	}
+	for _, leaf := range leaves {
+		dstPaths := make([]Path, 0)
+		for _, t := range srcMap[leaf.Path] {
+			dstPaths = append(dstPaths, t.Dst.Path)
+		}
+		if len(dstPaths) == 0 {
+			if !option.PreserveUnknown || option.isDropped(leaf.Path) {
+				continue
+			}
+			dstPaths = append(dstPaths, leaf.Path)
+		}
+		for _, dstPath := range dstPaths {
+			segments, err := concretePathSegments(dstPath, leaf.Indexes)
+			if err != nil {
+				return "", err
+			}
+			root = jsonTreeSet(root, segments, jsonRaw(leaf.Raw))
+		}
 	}
-	gjsonPath := transfers.String()
-	newS = gjson.Get(s, gjsonPath).String()
+	newS = jsonTreeString(root)
 	return newS, nil
 }
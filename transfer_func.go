@@ -4,7 +4,9 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
@@ -34,32 +36,103 @@ type CallFunc struct {
 	FuncName string         `json:"funcName"`
 }
 
+// Name 函数名称 package.FuncName
+func (cf CallFunc) Name() string {
+	return strings.TrimPrefix(fmt.Sprintf("%s.%s", cf.Package, cf.FuncName), ".")
+}
+
 type CallFuncs []CallFunc
 
 //go:embed callfunc.tpl
 var CallfuncTpl string
 
+var (
+	ERROR_CALL_FUNC_TEMPLATE_LANGUAGE = errors.New("unsport script language")
+)
+
+// callFuncTemplates 运行时注册的调用脚本模板,language => 模板内容,优先于 CallfuncTpl 中同名的模板
+var callFuncTemplates = map[string]string{}
+var callFuncTemplatesMu sync.RWMutex
+
+// RegisterCallFuncTemplate 注册调用脚本模板,tpl 为模板内容(不需要 define),数据为 CallFuncs;已存在的语言会被覆盖
+// 内置语言:go、javascript(兼容 goja)、lua、tengo
+func RegisterCallFuncTemplate(language string, tpl string) (err error) {
+	_, err = template.New(language).Parse(tpl)
+	if err != nil {
+		return err
+	}
+	callFuncTemplatesMu.Lock()
+	defer callFuncTemplatesMu.Unlock()
+	callFuncTemplates[language] = tpl
+	return nil
+}
+
+// CallFuncLanguages 支持生成调用脚本的语言
+func CallFuncLanguages() (languages []string) {
+	t, err := callFuncTemplate()
+	if err != nil {
+		return nil
+	}
+	languages = make([]string, 0)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Name() != "" {
+			languages = append(languages, tmpl.Name())
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func callFuncTemplate() (t *template.Template, err error) {
+	t, err = template.New("").Parse(CallfuncTpl)
+	if err != nil {
+		return nil, err
+	}
+	callFuncTemplatesMu.RLock()
+	defer callFuncTemplatesMu.RUnlock()
+	for language, tpl := range callFuncTemplates {
+		_, err = t.New(language).Parse(tpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
 func (cfs CallFuncs) Script(language string) (script string, err error) {
-	t, err := template.New("").Parse(CallfuncTpl)
+	t, err := callFuncTemplate()
 	if err != nil {
 		return "", err
 	}
 	var w bytes.Buffer
 	tmpl := t.Lookup(language)
 	if tmpl == nil {
-		err = errors.Errorf("unsport script language:%s", language)
+		err = errors.WithMessagef(ERROR_CALL_FUNC_TEMPLATE_LANGUAGE, "language:%s", language)
+		return "", err
+	}
+	if language == "go" && len(cfs.Packages()) > 1 {
+		err = errors.WithMessagef(ERROR_CALL_FUNC_MULTI_PACKAGE, "packages:%s,use Scripts", strings.Join(cfs.Packages(), ","))
 		return "", err
 	}
 	err = tmpl.Execute(&w, cfs)
 	if err != nil {
 		return "", err
 	}
-	return w.String(), nil
+	formatter, ok := callFuncFormatters[language]
+	if !ok {
+		return w.String(), nil
+	}
+	formatted, err := formatter(w.Bytes())
+	if err != nil {
+		err = errors.WithMessagef(err, "format %s script:\n%s", language, w.String())
+		return "", err
+	}
+	return string(formatted), nil
 }
 
 func (cfs CallFuncs) FirstPackage() (packageName string) {
 	for _, cf := range cfs {
-		packageName = cf.Package
+		return cf.Package
 	}
 	return packageName
 }
@@ -71,6 +144,10 @@ type FuncParameter struct {
 	Name      string `json:"name"`
 	Path      Path   `json:"path"`
 	Type      string `json:"type"`
+	// Fields 对象参数的字段,数组参数的元素为对象时为元素的字段
+	Fields FuncParameters `json:"fields,omitempty"`
+	// ItemType 数组参数的元素为基础类型时的元素类型
+	ItemType string `json:"itemType,omitempty"`
 }
 
 func (fp FuncParameter) String() (s string) {
@@ -83,14 +160,16 @@ func (fp FuncParameter) String() (s string) {
 
 // TypeConvertFunc 类型转换函数
 func (fp FuncParameter) TypeConvertFunc() (fnName string) {
-	m := map[string]string{
-		"int": "ToInt", //使用 cast.XXX 方法
+	m := map[string]string{ //使用 cast.XXX 方法,string、object、array 不需要转换
+		"int":     "ToInt",
+		"int64":   "ToInt64",
+		"number":  "ToFloat64",
+		"float":   "ToFloat64",
+		"float64": "ToFloat64",
+		"bool":    "ToBool",
+		"boolean": "ToBool",
 	}
 	fnName = m[strings.ToLower(fp.Type)]
-	if strings.EqualFold(fnName, "String") {
-		fnName = ""
-	}
-
 	return fnName
 }
 
@@ -110,6 +189,14 @@ func (fps *FuncParameters) AddReplace(funcParameters ...FuncParameter) {
 		exists := false
 		for i, fp2 := range *fps {
 			if strings.EqualFold(fp2.String(), fp.String()) {
+				if fp.IsComplex() && fp2.IsComplex() { // 对象、数组参数合并字段
+					fields := append(FuncParameters{}, fp2.Fields...)
+					fields.AddReplace(fp.Fields...)
+					fp.Fields = fields
+					if fp.ItemType == "" {
+						fp.ItemType = fp2.ItemType
+					}
+				}
 				(*fps)[i] = fp
 				exists = true
 				break
@@ -180,6 +267,12 @@ func (fps FuncParameters) CallFuncs() (callFuncs CallFuncs) {
 		callFunc.Input, callFunc.Output = funcParams.SplitInOut()
 		callFuncs = append(callFuncs, callFunc)
 	}
+	sort.SliceStable(callFuncs, func(i, j int) bool { // 保证生成的脚本稳定
+		if callFuncs[i].Package != callFuncs[j].Package {
+			return callFuncs[i].Package < callFuncs[j].Package
+		}
+		return callFuncs[i].FuncName < callFuncs[j].FuncName
+	})
 	return
 }
 
@@ -207,42 +300,131 @@ func FilterFuncTransfers(allTransfers Transfers, subTransfers Transfers) (funcTr
 
 }
 
+var (
+	ERROR_TRANSFER_FUNC_CYCLE = errors.New("transfer func dependency cycle")
+)
+
+// SortTransferFuncs 获取 transfers 中的全部函数名,按依赖排序:函数出参的目标路径是另一个函数入参的来源路径(相同或父子路径)时,前者先执行
+// 无依赖关系的函数按名称排序,保证执行顺序稳定;存在循环依赖时返回 ERROR_TRANSFER_FUNC_CYCLE
+func SortTransferFuncs(transfers Transfers) (funcNames []string, err error) {
+	funcTransfers := transfers.GetByNamespace(Transfer_Top_Namespace_Func)
+	names := funcTransfers.GetSrcNamespace(Transfer_Direction_output)
+	sort.Strings(names)
+	inPaths, outPaths := make(map[string][]Path), make(map[string][]Path)
+	for _, funcName := range names {
+		in, out := funcTransfers.GetByNamespace(funcName).SplitInOut()
+		inPaths[funcName], outPaths[funcName] = in.GetAllDst(), out.GetAllDst()
+	}
+	dependents := make(map[string][]string) // 函数 => 依赖其出参的函数
+	inDegree := make(map[string]int)
+	for _, from := range names {
+		for _, to := range names {
+			if from == to || !pathsOverlap(outPaths[from], inPaths[to]) { // 出参覆盖自身入参(如原地格式化)不算依赖
+				continue
+			}
+			dependents[from] = append(dependents[from], to)
+			inDegree[to]++
+		}
+	}
+	ready := make([]string, 0)
+	for _, funcName := range names {
+		if inDegree[funcName] == 0 {
+			ready = append(ready, funcName)
+		}
+	}
+	funcNames = make([]string, 0, len(names))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		funcName := ready[0]
+		ready = ready[1:]
+		funcNames = append(funcNames, funcName)
+		for _, dependent := range dependents[funcName] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(funcNames) < len(names) {
+		cycle := make([]string, 0)
+		for _, funcName := range names {
+			if inDegree[funcName] > 0 {
+				cycle = append(cycle, funcName)
+			}
+		}
+		err = errors.WithMessagef(ERROR_TRANSFER_FUNC_CYCLE, "funcs:%s", strings.Join(cycle, ","))
+		return nil, err
+	}
+	return funcNames, nil
+}
+
+// pathsOverlap 两组路径中存在相同或父子关系的路径
+func pathsOverlap(paths []Path, others []Path) bool {
+	for _, path := range paths {
+		for _, other := range others {
+			a, b := path.String(), other.String()
+			if a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // CallTransferFunc 根据输入数据,以及目标key路径,从transfers中选者合适的函数,执行，将结果合并输入作为输出，主要用于填充输入数据
+// transfers 中的全部函数按 SortTransferFuncs 的顺序依次执行,前一个函数的结果合并到输入后作为下一个函数的输入
+// closure 为nil时使用 DefaultFuncRegistry 执行已注册的go函数;任意函数出错时返回错误,需要跳过、默认值、重试时使用 CallTransferFuncWithOption
 func CallTransferFunc(transfers Transfers, input []byte, closure func(funcname string, input []byte) (out []byte, err error)) (out []byte, err error) {
-	dstTransfers := transfers.GetByNamespace(Transfer_Top_Namespace_Func)
-	funcNames := dstTransfers.GetSrcNamespace(Transfer_Direction_output)
-	if len(funcNames) == 0 { // 没有函数名,说明本次无需转换
-		return input, nil
+	out, _, err = CallTransferFuncWithOption(transfers, input, CallFuncOption{Closure: closure})
+	if err != nil {
+		return nil, err
 	}
-	funcName := funcNames[0] //本函数只执行一个（如单个torm）
+	return out, nil
+}
+
+// missingFuncArgs 入参在输入数据中不存在时返回全部缺失的入参
+func missingFuncArgs(transfers Transfers, funcName string, input []byte) (err error) {
 	inputNamespace := JoinPath(funcName, Transfer_Direction_input)
 	inputTransfers := transfers.GetByNamespace(inputNamespace.String())
+	var errs MultiError
 	for _, t := range inputTransfers {
 		if !gjson.GetBytes(input, t.Dst.Path.String()).Exists() {
-			err = errors.Errorf("missing transfer func %s arg %s", funcName, t.Dst.Path)
-			return nil, err
+			errs.Append(&MissingArgError{Transfer: t.String(), FuncName: strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func), Path: t.Dst.Path})
 		}
 	}
-	funcTransfer := transfers.GetByNamespace(funcName)
+	return errs.ErrorOrNil()
+}
 
+// funcGjsonPaths 函数入参(输入数据 => 本地数据格式)、出参(本地数据格式 => 输入数据)的gjson 路径,去除命名空间后路径冲突时返回错误,避免参数被静默覆盖
+func funcGjsonPaths(transfers Transfers, funcName string) (inputGopath string, outputGopath string, err error) {
+	funcTransfer := transfers.GetByNamespace(funcName)
 	inputPathTransfers, outputPathTransfers := funcTransfer.SplitInOut()
 	namespaceInput := JoinPath(funcName, Transfer_Direction_input)   //去除命名空间
 	namespaceOutput := JoinPath(funcName, Transfer_Direction_output) // 补充命名空间
-	inputGopath := inputPathTransfers.Reverse().ModifyDstPath(func(path Path) (newPath Path) {
+	inputTransfers, _, err := inputPathTransfers.Reverse().ModifyDstPathWithPolicy(PathCollisionPolicy_Fail, func(path Path) (newPath Path) {
 		return path.TrimNamespace(namespaceInput.String())
-	}).GjsonPath()
-	outputGopath := outputPathTransfers.ModifySrcPath(func(path Path) (newPath Path) {
+	})
+	if err != nil {
+		err = errors.WithMessagef(err, "func %s input", funcName)
+		return "", "", err
+	}
+	outputTransfers, _, err := outputPathTransfers.ModifySrcPathWithPolicy(PathCollisionPolicy_Fail, func(path Path) (newPath Path) {
 		return path.TrimNamespace(namespaceOutput.String())
-	}).GjsonPath()
-	noNamespaceFuncName := strings.TrimPrefix(funcName, Transfer_Top_Namespace_Func)
-	//转换为代码中期望的数据格式
-	localInput := gjson.GetBytes(input, inputGopath).String()         // 转换为本地数据格式
-	localOut, err := closure(noNamespaceFuncName, []byte(localInput)) // 执行代码
+	})
 	if err != nil {
-		return nil, err
+		err = errors.WithMessagef(err, "func %s output", funcName)
+		return "", "", err
 	}
+	return inputTransfers.GjsonPath(), outputTransfers.GjsonPath(), nil
+}
+
+// mergeFuncOutput 函数出参转换为外部交互数据格式后合并到输入
+func mergeFuncOutput(input []byte, localOut []byte, outputGopath string) (out []byte, err error) {
 	imputMore := gjson.GetBytes(localOut, outputGopath).String() // 转换为外部交互数据格式
-	out, err = jsonpatch.MergePatch(input, []byte(imputMore))    // 合并输入
+	if imputMore == "" {
+		return input, nil // 没有出参
+	}
+	out, err = jsonpatch.MergePatch(input, []byte(imputMore)) // 合并输入
 	if err != nil {
 		return nil, err
 	}
@@ -2,12 +2,18 @@ package pathtransfer_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/dop251/goja"
 	"github.com/stretchr/testify/require"
 	"github.com/suifengpiao14/pathtransfer"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	lua "github.com/yuin/gopher-lua"
+	luajson "layeh.com/gopher-json"
 )
 
 func TestCallFunc(t *testing.T) {
@@ -113,3 +119,213 @@ categorizePagination.input.size@int:Dictionary.limit.size
 	funcTransfers := pathtransfer.FilterFuncTransfers(allTransfers, limitTransfers)
 	require.Equal(t, 4, len(funcTransfers))
 }
+
+func TestCallTransferFuncOrder(t *testing.T) {
+	registry := pathtransfer.NewFuncRegistry()
+	registry.MustRegister("vocabulary.TrimName", strings.TrimSpace, []string{"name"}, []string{"name"})
+	registry.MustRegister("vocabulary.Greet", func(name string) string { return "hello " + name }, []string{"name"}, []string{"greeting"})
+	transfers := pathtransfer.Parse(`
+func.vocabulary.Greet.input.name:data.userName
+func.vocabulary.Greet.output.greeting:data.greeting
+func.vocabulary.TrimName.input.name:user.name
+func.vocabulary.TrimName.output.name:data.userName
+	`)
+	funcNames, err := pathtransfer.SortTransferFuncs(transfers)
+	require.NoError(t, err)
+	require.Equal(t, []string{"func.vocabulary.TrimName", "func.vocabulary.Greet"}, funcNames)
+
+	out, err := pathtransfer.CallTransferFunc(transfers, []byte(`{"user":{"name":" zhang "}}`), registry.Call)
+	require.NoError(t, err)
+	require.Equal(t, "hello zhang", gjson.GetBytes(out, "data.greeting").String())
+
+	t.Run("cycle", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.vocabulary.A.input.x:data.x
+func.vocabulary.A.output.y:data.y
+func.vocabulary.B.input.y:data.y.value
+func.vocabulary.B.output.x:data.x
+func.vocabulary.C.input.z:data.z
+func.vocabulary.C.output.z:data.z
+	`)
+		_, err := pathtransfer.SortTransferFuncs(transfers)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_CYCLE)
+		require.ErrorContains(t, err, "func.vocabulary.A,func.vocabulary.B")
+		_, err = pathtransfer.CallTransferFunc(transfers, []byte(`{}`), registry.Call)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_FUNC_CYCLE)
+	})
+}
+
+func TestCallFuncScript(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.vocabulary.SetLimit.input.index@int:Dictionary.pagination.index
+func.vocabulary.SetLimit.input.size@int:Dictionary.pagination.size
+func.vocabulary.SetLimit.output.offset@int:Dictionary.limit.offset
+func.vocabulary.SetLimit.output.size@int:Dictionary.limit.size
+	`)
+	require.Subset(t, pathtransfer.CallFuncLanguages(), []string{"go", "javascript", "lua", "tengo"})
+	t.Run("javascript", func(t *testing.T) {
+		script, err := transfers.GetCallFnScript("javascript")
+		require.NoError(t, err)
+		require.Contains(t, script, "function CallSetLimit(input) {")
+		require.Contains(t, script, `var index = __get(__input, "index");`)
+		require.Contains(t, script, `__set(__out, "size", __result[1]);`)
+		out, err := runJavascriptCall("function SetLimit(index, size) { return [(index - 1) * size, size]; }\n"+script, "CallSetLimit", `{"index":"3","size":10}`)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"offset":20,"size":10}`, out)
+	})
+	t.Run("lua", func(t *testing.T) {
+		script, err := transfers.GetCallFnScript("lua")
+		require.NoError(t, err)
+		require.Contains(t, script, "function CallSetLimit(input)")
+		require.Contains(t, script, "local offset,size = SetLimit(index,size)")
+		out, err := runLuaCall("function SetLimit(index, size) return (index - 1) * size, size end\n"+script, "CallSetLimit", `{"index":"3","size":10}`)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"offset":20,"size":10}`, out)
+	})
+	t.Run("tengo", func(t *testing.T) {
+		script, err := transfers.GetCallFnScript("tengo")
+		require.NoError(t, err)
+		script = "SetLimit := func(index, size) { return [(index-1)*size, size] }\n" + script + "\nout := CallSetLimit(input)\n"
+		s := tengo.NewScript([]byte(script))
+		s.SetImports(stdlib.GetModuleMap("json", "text"))
+		err = s.Add("input", `{"index":"3","size":10}`)
+		require.NoError(t, err)
+		compiled, err := s.Run()
+		require.NoError(t, err)
+		require.JSONEq(t, `{"offset":20,"size":10}`, compiled.Get("out").String())
+	})
+	t.Run("reserved names and bool", func(t *testing.T) {
+		transfers := pathtransfer.Parse(`
+func.vocabulary.Check.input.end@int:data.end
+func.vocabulary.Check.input.user-id@string:data.userId
+func.vocabulary.Check.input.function@string:data.fn
+func.vocabulary.Check.input.active@bool:data.active
+func.vocabulary.Check.output.result@string:data.result
+		`)
+		input := `{"end":"2","user-id":7,"function":"f","active":"true"}`
+		expected := `{"result":"2-7-f-true"}`
+		script, err := transfers.GetCallFnScript("javascript")
+		require.NoError(t, err)
+		out, err := runJavascriptCall("function Check(end, userId, fn, active) { return [end, userId, fn, active].join('-'); }\n"+script, "CallCheck", input)
+		require.NoError(t, err)
+		require.JSONEq(t, expected, out)
+
+		script, err = transfers.GetCallFnScript("lua")
+		require.NoError(t, err)
+		out, err = runLuaCall("function Check(e, userId, fn, active) return e .. '-' .. userId .. '-' .. fn .. '-' .. tostring(active) end\n"+script, "CallCheck", input)
+		require.NoError(t, err)
+		require.JSONEq(t, expected, out)
+
+		script, err = transfers.GetCallFnScript("tengo")
+		require.NoError(t, err)
+		script = "Check := func(e, userId, fn, active) { return format(\"%d-%s-%s-%t\", e, userId, fn, active) }\n" + script + "\nout := CallCheck(input)\n"
+		s := tengo.NewScript([]byte(script))
+		s.SetImports(stdlib.GetModuleMap("json", "text"))
+		require.NoError(t, s.Add("input", input))
+		compiled, err := s.Run()
+		require.NoError(t, err)
+		require.JSONEq(t, expected, compiled.Get("out").String())
+	})
+	t.Run("register", func(t *testing.T) {
+		_, err := transfers.GetCallFnScript("python")
+		require.ErrorIs(t, err, pathtransfer.ERROR_CALL_FUNC_TEMPLATE_LANGUAGE)
+		err = pathtransfer.RegisterCallFuncTemplate("python", `{{range .}}def Call{{.FuncName}}(input): pass{{end}}`)
+		require.NoError(t, err)
+		script, err := transfers.GetCallFnScript("python")
+		require.NoError(t, err)
+		require.Equal(t, "def CallSetLimit(input): pass", script)
+		err = pathtransfer.RegisterCallFuncTemplate("bad", `{{range .}}`)
+		require.Error(t, err)
+	})
+}
+
+// runJavascriptCall 使用 goja 执行生成的调用脚本
+func runJavascriptCall(script string, callFuncName string, input string) (out string, err error) {
+	vm := goja.New()
+	if _, err = vm.RunString(script); err != nil {
+		return "", err
+	}
+	call, ok := goja.AssertFunction(vm.Get(callFuncName))
+	if !ok {
+		return "", fmt.Errorf("%s is not a function", callFuncName)
+	}
+	value, err := call(goja.Undefined(), vm.ToValue(input))
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// runLuaCall 使用 gopher-lua 执行生成的调用脚本,json 模块使用 gopher-json
+func runLuaCall(script string, callFuncName string, input string) (out string, err error) {
+	L := lua.NewState()
+	defer L.Close()
+	luajson.Preload(L)
+	if err = L.DoString(script); err != nil {
+		return "", err
+	}
+	err = L.CallByParam(lua.P{Fn: L.GetGlobal(callFuncName), NRet: 1, Protect: true}, lua.LString(input))
+	if err != nil {
+		return "", err
+	}
+	return L.Get(-1).String(), nil
+}
+
+func TestFuncParameterFields(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+func.vocabulary.Search.input.pagination.index@int:data.pagination.index
+func.vocabulary.Search.input.pagination.size@int:data.pagination.size
+func.vocabulary.Search.input.tags#@string:data.tags
+func.vocabulary.Search.input.items#.id@int:data.items.#.id
+func.vocabulary.Search.output.total@int:data.total
+	`)
+	callFuncs, err := transfers.CallFuncs()
+	require.NoError(t, err)
+	require.Len(t, callFuncs, 1)
+	in := callFuncs[0].Input
+	require.Equal(t, "pagination,tags,items", in.Names())
+	require.Equal(t, "object", in[0].Type)
+	require.Equal(t, "index,size", in[0].Fields.Names())
+	require.Equal(t, pathtransfer.Path("func.vocabulary.Search.input.pagination.size"), in[0].Fields[1].Path)
+	require.Equal(t, "int", in[0].Fields[1].Type)
+	require.Equal(t, "array", in[1].Type)
+	require.Equal(t, "string", in[1].ItemType)
+	require.Equal(t, "array", in[2].Type)
+	require.Equal(t, "id", in[2].Fields.Names())
+	require.Equal(t, "[]struct {\nId int `json:\"id\"`\n}", in[2].GoType())
+}
+
+type userFilter struct {
+	Name string `json:"name"`
+	Ages []int  `json:"ages"`
+}
+
+type userAge struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestFuncRegistryStructArgs(t *testing.T) {
+	registry := pathtransfer.NewFuncRegistry()
+	registry.MustRegister("vocabulary.FindUsers", func(filter userFilter) (users []userAge) {
+		for _, age := range filter.Ages {
+			users = append(users, userAge{Name: filter.Name, Age: age})
+		}
+		return users
+	}, []string{"filter"}, []string{"users"})
+	rf, ok := registry.Get("vocabulary.FindUsers")
+	require.True(t, ok)
+	require.Equal(t, "name,ages", rf.Input[0].Fields.Names())
+	require.Equal(t, "int", rf.Input[0].Fields[1].ItemType)
+	require.Equal(t, "array", rf.Output[0].Type)
+	require.Equal(t, "name,age", rf.Output[0].Fields.Names())
+
+	transfers := pathtransfer.Parse(`
+func.vocabulary.FindUsers.input.filter.name:query.name
+func.vocabulary.FindUsers.input.filter.ages:query.ages
+func.vocabulary.FindUsers.output.users:data.users
+	`)
+	out, err := pathtransfer.CallTransferFunc(transfers, []byte(`{"query":{"name":"zhang","ages":[18,20]}}`), registry.Call)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"name":"zhang","age":18},{"name":"zhang","age":20}]`, gjson.GetBytes(out, "data.users").Raw)
+}
@@ -161,3 +161,120 @@ func TestPathBaseName(t *testing.T) {
 	require.Equal(t, "pagination.index", baseName)
 
 }
+
+func TestRebuildJsonWithOption(t *testing.T) {
+	jsonStr := `{"Fuser_id":1,"Fuser_name":"张三","Faddress":{"Fcity_name":"深圳","Fzip_code":null},"Forders":[{"Forder_id":"a1","Fitems":[{"Fsku_id":1},{"Fsku_id":2,"Fremark":""}]},{"Forder_id":"a2","Fitems":[]}],"Ftags":["x","y"],"Fext":{}}`
+	t.Run("identity", func(t *testing.T) {
+		newJson, err := pathtransfer.RebuildJsonWithOption(jsonStr, pathtransfer.RebuildJsonOption{})
+		require.NoError(t, err)
+		require.JSONEq(t, jsonStr, newJson)
+	})
+	t.Run("trim prefix and camel case", func(t *testing.T) {
+		newJson, err := pathtransfer.RebuildJsonWithOption(jsonStr, pathtransfer.RebuildJsonOption{
+			TrimKeyPrefix: "F",
+			KeyCase:       pathtransfer.KeyCase_CamelCase,
+		})
+		require.NoError(t, err)
+		expected := `{"userId":1,"userName":"张三","address":{"cityName":"深圳","zipCode":null},"orders":[{"orderId":"a1","items":[{"skuId":1},{"skuId":2,"remark":""}]},{"orderId":"a2","items":[]}],"tags":["x","y"],"ext":{}}`
+		require.JSONEq(t, expected, newJson)
+	})
+	t.Run("drop keys", func(t *testing.T) {
+		newJson, err := pathtransfer.RebuildJsonWithOption(jsonStr, pathtransfer.RebuildJsonOption{
+			DropKeys: []pathtransfer.Path{"Faddress", "Forders.#.Fitems.#.Fremark", "Ftags"},
+		})
+		require.NoError(t, err)
+		expected := `{"Fuser_id":1,"Fuser_name":"张三","Forders":[{"Forder_id":"a1","Fitems":[{"Fsku_id":1},{"Fsku_id":2}]},{"Forder_id":"a2","Fitems":[]}],"Fext":{}}`
+		require.JSONEq(t, expected, newJson)
+	})
+	t.Run("preserve unknown", func(t *testing.T) {
+		modifyTransferFn := func(transfers pathtransfer.Transfers) (newTransfers pathtransfer.Transfers) {
+			return transfers.FilterBySrc("Fuser_id").ModifyDstPath(func(path pathtransfer.Path) (newPath pathtransfer.Path) {
+				return "id"
+			})
+		}
+		newJson, err := pathtransfer.RebuildJsonWithOption(`{"Fuser_id":1,"Faddress":{"Fcity_name":"深圳"}}`, pathtransfer.RebuildJsonOption{
+			ModifyTransferFn: modifyTransferFn,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1}`, newJson)
+		newJson, err = pathtransfer.RebuildJsonWithOption(`{"Fuser_id":1,"Faddress":{"Fcity_name":"深圳"}}`, pathtransfer.RebuildJsonOption{
+			ModifyTransferFn: modifyTransferFn,
+			PreserveUnknown:  true,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":1,"Faddress":{"Fcity_name":"深圳"}}`, newJson)
+	})
+	t.Run("collision", func(t *testing.T) {
+		data := `{"list":[{"user_id":1,"userId":2}]}`
+		_, err := pathtransfer.RebuildJsonWithOption(data, pathtransfer.RebuildJsonOption{
+			KeyCase:          pathtransfer.KeyCase_CamelCase,
+			ErrorOnCollision: true,
+		})
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_KEY_COLLISION)
+		newJson, err := pathtransfer.RebuildJsonWithOption(data, pathtransfer.RebuildJsonOption{
+			KeyCase: pathtransfer.KeyCase_CamelCase,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"list":[{"userId":2}]}`, newJson)
+	})
+	t.Run("root array", func(t *testing.T) {
+		newJson, err := pathtransfer.RebuildJsonWithOption(`[{"user_id":1},[1,2],"x"]`, pathtransfer.RebuildJsonOption{
+			KeyCase: pathtransfer.KeyCase_CamelCase,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"userId":1},[1,2],"x"]`, newJson)
+	})
+}
+
+func TestModifyDstPathWithPolicy(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+db.user.Fuser_id:user_id
+db.user.FuserId:userId
+db.user.Fname:name
+	`)
+	t.Run("fail", func(t *testing.T) {
+		_, collisions, err := transfers.ModifyDstPathWithPolicy(pathtransfer.PathCollisionPolicy_Fail, pathtransfer.PathModifyFnSmallCameCase)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_KEY_COLLISION)
+		require.Equal(t, 1, len(collisions))
+		require.Equal(t, pathtransfer.Path("userId"), collisions[0].Path)
+		require.Equal(t, pathtransfer.Path("user_id"), collisions[0].FirstOrigin)
+		require.Equal(t, pathtransfer.Path("userId"), collisions[0].SecondOrigin)
+	})
+	t.Run("keepFirst", func(t *testing.T) {
+		nt, collisions, err := transfers.ModifyDstPathWithPolicy(pathtransfer.PathCollisionPolicy_KeepFirst, pathtransfer.PathModifyFnSmallCameCase)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(collisions))
+		require.Equal(t, "db.user.Fuser_id:userId\ndb.user.Fname:name\n", nt.String())
+	})
+	t.Run("suffix", func(t *testing.T) {
+		nt, collisions, err := transfers.ModifyDstPathWithPolicy(pathtransfer.PathCollisionPolicy_Suffix, pathtransfer.PathModifyFnSmallCameCase)
+		require.NoError(t, err)
+		require.Equal(t, pathtransfer.Path("userId_2"), collisions[0].NewPath)
+		require.Equal(t, "db.user.Fuser_id:userId\ndb.user.FuserId:userId_2\ndb.user.Fname:name\n", nt.String())
+	})
+	t.Run("src", func(t *testing.T) {
+		_, collisions, err := transfers.ModifySrcPathWithPolicy(pathtransfer.PathCollisionPolicy_Fail, pathtransfer.PathModifyFnSnakeCase)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_KEY_COLLISION)
+		require.Equal(t, pathtransfer.Path("db.user.fuser_id"), collisions[0].Path)
+	})
+	t.Run("emptyPolicy", func(t *testing.T) {
+		_, _, err := transfers.ModifyDstPathWithPolicy("", pathtransfer.PathModifyFnSmallCameCase)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_KEY_COLLISION)
+	})
+	t.Run("unknownPolicy", func(t *testing.T) {
+		_, _, err := transfers.ModifyDstPathWithPolicy("keepLast", pathtransfer.PathModifyFnSmallCameCase)
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_PATH_COLLISION_POLICY)
+	})
+}
+
+func TestTransfersReverse(t *testing.T) {
+	transfers := pathtransfer.Parse(`
+user.name:Api.name
+items.#.price|sum:Api.order.total
+user.email:Api.email when user.name=="张三"
+user.user_name??user.userName:Api.userName
+user.nick:Api.nick null=emptyAsNull
+=1+1:Api.count
+	`)
+	require.Equal(t, "Api.name:user.name\nApi.userName:user.user_name\nApi.nick:user.nick null=emptyAsNull\n", transfers.Reverse().String())
+}
@@ -0,0 +1,19 @@
+package transferfunc
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// Md5 md5 摘要,小写十六进制
+func Md5(value string) (hash string) {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sha1 sha1 摘要,小写十六进制
+func Sha1(value string) (hash string) {
+	sum := sha1.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,32 @@
+package transferfunc
+
+import (
+	"strings"
+)
+
+// MaskPhone 手机号脱敏,保留前3位和后4位,如 138****5678;长度不足时保留首尾各1位
+func MaskPhone(phone string) (masked string) {
+	runes := []rune(phone)
+	switch {
+	case len(runes) >= 11:
+		return maskRunes(runes, 3, 4)
+	case len(runes) > 2:
+		return maskRunes(runes, 1, 1)
+	}
+	return phone
+}
+
+// MaskEmail 邮箱脱敏,用户名保留首字符,如 z***@example.com
+func MaskEmail(email string) (masked string) {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return email
+	}
+	name := []rune(email[:at])
+	return string(name[:1]) + "***" + email[at:]
+}
+
+// maskRunes 保留前 head 个、后 tail 个字符,中间替换为 *
+func maskRunes(runes []rune, head int, tail int) (masked string) {
+	return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
@@ -0,0 +1,25 @@
+package transferfunc
+
+import (
+	"math"
+)
+
+// Round 四舍五入,precision 为保留的小数位数,可以为负数(如 -2 表示取整到百位)
+func Round(value float64, precision int) (newValue float64) {
+	return roundFn(value, precision, math.Round)
+}
+
+// Ceil 向上取整到指定小数位数
+func Ceil(value float64, precision int) (newValue float64) {
+	return roundFn(value, precision, math.Ceil)
+}
+
+// Floor 向下取整到指定小数位数
+func Floor(value float64, precision int) (newValue float64) {
+	return roundFn(value, precision, math.Floor)
+}
+
+func roundFn(value float64, precision int, fn func(float64) float64) (newValue float64) {
+	pow := math.Pow(10, float64(precision))
+	return fn(value*pow) / pow
+}
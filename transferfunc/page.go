@@ -0,0 +1,54 @@
+package transferfunc
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ERROR_CURSOR_INVALID = errors.New("invalid cursor")
+)
+
+// PageOffset 页码(从1开始)转换为SQL分页,page 小于1时按第1页
+func PageOffset(page int, size int) (offset int, limit int) {
+	if page < 1 {
+		page = 1
+	}
+	return (page - 1) * size, size
+}
+
+// OffsetPage SQL分页转换为页码(从1开始)
+func OffsetPage(offset int, limit int) (page int, size int) {
+	if limit <= 0 {
+		return 1, limit
+	}
+	return offset/limit + 1, limit
+}
+
+// TotalPages 总页数
+func TotalPages(total int, size int) (pages int) {
+	if size <= 0 {
+		return 0
+	}
+	return (total + size - 1) / size
+}
+
+// CursorEncode 游标分页,将最后一条记录的标识编码为游标
+func CursorEncode(lastID string) (cursor string) {
+	if lastID == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(lastID))
+}
+
+// CursorDecode 游标解码为最后一条记录的标识,游标为空表示第一页
+func CursorDecode(cursor string) (lastID string, err error) {
+	if cursor == "" {
+		return "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ERROR_CURSOR_INVALID
+	}
+	return string(b), nil
+}
@@ -0,0 +1,62 @@
+package transferfunc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Trim 删除前后空白字符
+func Trim(value string) (newValue string) {
+	return strings.TrimSpace(value)
+}
+
+// Upper 转大写
+func Upper(value string) (newValue string) {
+	return strings.ToUpper(value)
+}
+
+// Lower 转小写
+func Lower(value string) (newValue string) {
+	return strings.ToLower(value)
+}
+
+// Substr 按字符(非字节)截取,start 从0开始,为负数时从末尾计算,length 小于0时截取到末尾
+func Substr(value string, start int, length int) (newValue string) {
+	runes := []rune(value)
+	if start < 0 {
+		start += len(runes)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= len(runes) {
+		return ""
+	}
+	end := len(runes)
+	if length >= 0 && start+length < end {
+		end = start + length
+	}
+	return string(runes[start:end])
+}
+
+// Split 按分隔符拆分,value 为空时返回空数组
+func Split(value string, sep string) (items []string) {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, sep)
+}
+
+// Join 按分隔符拼接
+func Join(items []string, sep string) (value string) {
+	return strings.Join(items, sep)
+}
+
+// RegexReplace 正则替换,replacement 中可以使用 $1 引用分组
+func RegexReplace(value string, pattern string, replacement string) (newValue string, err error) {
+	reg, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return reg.ReplaceAllString(value, replacement), nil
+}
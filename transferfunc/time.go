@@ -0,0 +1,48 @@
+package transferfunc
+
+import (
+	"time"
+)
+
+const (
+	Time_Layout_Default = "2006-01-02 15:04:05" // 默认时间格式
+)
+
+func timeLayout(layout string) string {
+	if layout == "" {
+		return Time_Layout_Default
+	}
+	return layout
+}
+
+// TimeFormat 时间格式转换,layout 使用go时间格式,为空时为 Time_Layout_Default,按本地时区解析
+func TimeFormat(value string, fromLayout string, toLayout string) (newValue string, err error) {
+	if value == "" {
+		return "", nil
+	}
+	t, err := time.ParseInLocation(timeLayout(fromLayout), value, time.Local)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(timeLayout(toLayout)), nil
+}
+
+// UnixToTime 时间戳(秒)格式化,timestamp 为0时返回空
+func UnixToTime(timestamp int64, layout string) (value string) {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).Format(timeLayout(layout))
+}
+
+// TimeToUnix 时间转换为时间戳(秒),value 为空时返回0
+func TimeToUnix(value string, layout string) (timestamp int64, err error) {
+	if value == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation(timeLayout(layout), value, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
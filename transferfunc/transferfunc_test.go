@@ -0,0 +1,56 @@
+package transferfunc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer/transferfunc"
+)
+
+func TestString(t *testing.T) {
+	require.Equal(t, "中文", transferfunc.Substr("你好中文字符", 2, 2))
+	require.Equal(t, "字符", transferfunc.Substr("你好中文字符", -2, -1))
+	require.Equal(t, "", transferfunc.Substr("abc", 5, 1))
+	require.Equal(t, []string{}, transferfunc.Split("", ","))
+	require.Equal(t, "a-b", transferfunc.Join(transferfunc.Split("a,b", ","), "-"))
+	newValue, err := transferfunc.RegexReplace("2024-01-02", `(\d+)-(\d+)-(\d+)`, "$3/$2/$1")
+	require.NoError(t, err)
+	require.Equal(t, "02/01/2024", newValue)
+	_, err = transferfunc.RegexReplace("a", `(`, "")
+	require.Error(t, err)
+}
+
+func TestPage(t *testing.T) {
+	offset, limit := transferfunc.PageOffset(3, 20)
+	require.Equal(t, []int{40, 20}, []int{offset, limit})
+	page, size := transferfunc.OffsetPage(offset, limit)
+	require.Equal(t, []int{3, 20}, []int{page, size})
+	require.Equal(t, 3, transferfunc.TotalPages(41, 20))
+	lastID, err := transferfunc.CursorDecode(transferfunc.CursorEncode("1001"))
+	require.NoError(t, err)
+	require.Equal(t, "1001", lastID)
+	_, err = transferfunc.CursorDecode("!!")
+	require.ErrorIs(t, err, transferfunc.ERROR_CURSOR_INVALID)
+}
+
+func TestTime(t *testing.T) {
+	value, err := transferfunc.TimeFormat("2024-01-02 03:04:05", "", "2006/01/02")
+	require.NoError(t, err)
+	require.Equal(t, "2024/01/02", value)
+	timestamp, err := transferfunc.TimeToUnix("2024-01-02 03:04:05", "")
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02 03:04:05", transferfunc.UnixToTime(timestamp, ""))
+	require.Equal(t, "", transferfunc.UnixToTime(0, ""))
+}
+
+func TestHashMaskRound(t *testing.T) {
+	require.Equal(t, "900150983cd24fb0d6963f7d28e17f72", transferfunc.Md5("abc"))
+	require.Equal(t, "a9993e364706816aba3e25717850c26c9cd0d89d", transferfunc.Sha1("abc"))
+	require.Equal(t, "138****5678", transferfunc.MaskPhone("13812345678"))
+	require.Equal(t, "1***5", transferfunc.MaskPhone("12345"))
+	require.Equal(t, "z***@example.com", transferfunc.MaskEmail("zhang@example.com"))
+	require.Equal(t, "invalid", transferfunc.MaskEmail("invalid"))
+	require.Equal(t, 3.14, transferfunc.Round(3.14159, 2))
+	require.Equal(t, 3.15, transferfunc.Ceil(3.141, 2))
+	require.Equal(t, 1200.0, transferfunc.Floor(1234, -2))
+}
@@ -0,0 +1,130 @@
+package pathtransfer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	UnmappedMode_Drop        = "drop"        // 丢弃未映射的来源字段(默认)
+	UnmappedMode_Passthrough = "passthrough" // 未映射的来源字段原样输出(可通过 PathModifyFn 改名),代理接口使用
+	UnmappedMode_Strict      = "strict"      // 来源存在未映射的字段时报错
+)
+
+var (
+	ERROR_TRANSFER_UNMAPPED_FIELD = errors.New("unmapped field")
+	ERROR_TRANSFER_UNMAPPED_MODE  = errors.New("unknown unmapped mode")
+)
+
+// normalizeUnmappedMode 统一大小写,为空时为 UnmappedMode_Drop,不支持的模式返回错误
+func normalizeUnmappedMode(mode string) (normalized string, err error) {
+	normalized = strings.ToLower(strings.TrimSpace(mode))
+	switch normalized {
+	case "":
+		normalized = UnmappedMode_Drop
+	case UnmappedMode_Drop, UnmappedMode_Passthrough, UnmappedMode_Strict:
+	default:
+		err = errors.WithMessagef(ERROR_TRANSFER_UNMAPPED_MODE, "got:%s", mode)
+		return "", err
+	}
+	return normalized, nil
+}
+
+// srcPathCovers 判断来源路径是否覆盖叶子节点路径,来源路径为叶子节点路径的前缀即覆盖(映射对象时覆盖所有子节点)
+// 数组下标和# 等价,gjson 查询、modifier 所在层级及之后的部分视为全部覆盖
+func srcPathCovers(srcPath Path, leafPath Path) bool {
+	srcSegments := splitPath(normalizeDstPath(srcPath))
+	leafSegments := splitPath(leafPath)
+	for i, seg := range srcSegments {
+		if seg == Path_Key {
+			return false
+		}
+		if seg == Path_Wildcard && i < len(leafSegments) {
+			continue
+		}
+		if strings.ContainsAny(seg, "@(*?") {
+			return true
+		}
+		if i >= len(leafSegments) {
+			return false
+		}
+		leafSeg := leafSegments[i]
+		if seg == leafSeg || leafSeg == "#" && isArrayIndex(seg) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func isArrayIndex(seg string) bool {
+	if isArraySegment(seg) {
+		return true
+	}
+	for _, c := range seg {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return seg != ""
+}
+
+// unmappedLeaves 获取没有被任何转换来源路径覆盖的叶子节点
+func (t Transfers) unmappedLeaves(s string) (leaves []jsonLeaf) {
+	srcPaths := make([]Path, 0)
+	for _, transfer := range t {
+		srcPaths = append(srcPaths, transfer.SrcPaths()...)
+	}
+	leaves = make([]jsonLeaf, 0)
+	for _, leaf := range getAllJsonLeaf(s) {
+		if leaf.Path == "" {
+			continue
+		}
+		covered := false
+		for _, srcPath := range srcPaths {
+			if srcPathCovers(srcPath, leaf.Path) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			leaves = append(leaves, leaf)
+		}
+	}
+	return leaves
+}
+
+// uniqueLeafPaths 叶子节点路径去重(数组元素的路径相同)
+func uniqueLeafPaths(leaves []jsonLeaf) (paths []string) {
+	paths = make([]string, 0)
+	m := map[Path]struct{}{}
+	for _, leaf := range leaves {
+		if _, ok := m[leaf.Path]; ok {
+			continue
+		}
+		m[leaf.Path] = struct{}{}
+		paths = append(paths, leaf.Path.String())
+	}
+	return paths
+}
+
+// passthroughLeaves 将未映射的叶子节点写入输出,目标位置已经有值时以转换结果为准
+func passthroughLeaves(root any, trace Trace, leaves []jsonLeaf, pathModifyFn PathModifyFn) (newRoot any, newTrace Trace) {
+	for _, leaf := range leaves {
+		dst := leaf.Path
+		if pathModifyFn != nil {
+			dst = pathModifyFn(dst)
+		}
+		segments, err := concretePathSegments(dst, leaf.Indexes)
+		if err != nil || jsonTreeGet(root, segments) != nil {
+			continue
+		}
+		root = jsonTreeSet(root, segments, jsonRaw(leaf.Raw))
+		if _, ok := trace.GetByDst(dst); !ok {
+			transfer := Transfer{Src: TransferUnit{Path: leaf.Path}, Dst: TransferUnit{Path: dst}}
+			trace = append(trace, TraceItem{Dst: dst, Transfer: transfer.String(), Src: leaf.Path, Raw: leaf.Raw, Value: leaf.Raw})
+		}
+	}
+	return root, trace
+}
@@ -0,0 +1,53 @@
+package pathtransfer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+)
+
+func TestApplyUnmapped(t *testing.T) {
+	data := `{"user":{"user_id":"1","user_name":"张三","extra":{"level":2}},"items":[{"sku_id":1,"qty":2},{"sku_id":2,"qty":1}],"trace_id":"abc"}`
+	transfers := pathtransfer.Parse(`
+user.user_id:user.userId@int
+items.#.sku_id:items.#.skuId
+user.extra:user.extra
+	`)
+	t.Run("drop", func(t *testing.T) {
+		out, _, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"user":{"userId":1,"extra":{"level":2}},"items":[{"skuId":1},{"skuId":2}]}`, string(out))
+	})
+	t.Run("passthrough", func(t *testing.T) {
+		out, trace, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{UnmappedMode: pathtransfer.UnmappedMode_Passthrough})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"user":{"userId":1,"extra":{"level":2},"user_name":"张三"},"items":[{"skuId":1,"qty":2},{"skuId":2,"qty":1}],"trace_id":"abc"}`, string(out))
+		item, ok := trace.GetByDst("trace_id")
+		require.True(t, ok)
+		require.Equal(t, pathtransfer.Path("trace_id"), item.Src)
+	})
+	t.Run("passthrough rename", func(t *testing.T) {
+		out, _, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{
+			UnmappedMode:         pathtransfer.UnmappedMode_Passthrough,
+			UnmappedPathModifyFn: pathtransfer.PathModifyFnSmallCameCase,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"user":{"userId":1,"extra":{"level":2},"userName":"张三"},"items":[{"skuId":1,"qty":2},{"skuId":2,"qty":1}],"traceId":"abc"}`, string(out))
+	})
+	t.Run("strict", func(t *testing.T) {
+		_, _, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{UnmappedMode: pathtransfer.UnmappedMode_Strict})
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_UNMAPPED_FIELD)
+		require.Contains(t, err.Error(), "user.user_name,items.#.qty,trace_id")
+
+		covered := append(transfers, pathtransfer.Parse("user.user_name:user.name\nitems.#.qty:items.#.qty\n=upper(trace_id):traceId")...)
+		_, _, err = covered.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{UnmappedMode: pathtransfer.UnmappedMode_Strict})
+		require.NoError(t, err)
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, _, err := transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{UnmappedMode: "passThrough"})
+		require.NoError(t, err)
+		_, _, err = transfers.ApplyWithOption([]byte(data), pathtransfer.ApplyOption{UnmappedMode: "keep"})
+		require.ErrorIs(t, err, pathtransfer.ERROR_TRANSFER_UNMAPPED_MODE)
+	})
+}
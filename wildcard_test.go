@@ -0,0 +1,89 @@
+package pathtransfer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/suifengpiao14/pathtransfer"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyWildcard(t *testing.T) {
+	t.Run("object to array", func(t *testing.T) {
+		data := `{"users":{"1001":{"name":"张三","age":"18"},"1002":{"name":"李四","age":"20"}}}`
+		transfers := pathtransfer.Parse(`
+users.*.@key:Api.users.#.id@int
+users.*.name:Api.users.#.name
+users.*.age:Api.users.#.age@int
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		expected := `{"Api":{"users":[{"id":1001,"name":"张三","age":18},{"id":1002,"name":"李四","age":20}]}}`
+		require.JSONEq(t, expected, string(out))
+
+		gjsonOut := gjson.Get(data, transfers.GjsonPath()).String()
+		require.JSONEq(t, expected, gjsonOut)
+	})
+	t.Run("array to object", func(t *testing.T) {
+		data := `{"users":[{"id":1001,"name":"张三"},{"id":1002,"name":"李四","tags":["a"]}]}`
+		transfers := pathtransfer.Parse(`
+users.#.id@int:Api.users.*.@key
+users.#.name:Api.users.*.name
+users.#.tags:Api.users.*.tags
+code:Api.code
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		expected := `{"Api":{"users":{"1001":{"name":"张三"},"1002":{"name":"李四","tags":["a"]}}}}`
+		require.JSONEq(t, expected, string(out))
+
+		gjsonOut := gjson.Get(data, transfers.GjsonPath()).String()
+		require.JSONEq(t, expected, gjsonOut)
+
+		reversed, err := transfers.Reverse().Apply(out)
+		require.NoError(t, err)
+		require.JSONEq(t, data, string(reversed))
+	})
+	t.Run("nested", func(t *testing.T) {
+		data := `{"shops":{"s1":{"goods":{"g1":{"price":1},"g2":{"price":2}}},"s2":{"goods":{"g3":{"price":3}}}}}`
+		transfers := pathtransfer.Parse(`
+shops.*.@key:shops.#.shopId
+shops.*.goods.*.@key:shops.#.goods.#.goodsId
+shops.*.goods.*.price:shops.#.goods.#.price
+		`)
+		out, err := transfers.Apply([]byte(data))
+		require.NoError(t, err)
+		expected := `{"shops":[{"shopId":"s1","goods":[{"goodsId":"g1","price":1},{"goodsId":"g2","price":2}]},{"shopId":"s2","goods":[{"goodsId":"g3","price":3}]}]}`
+		require.JSONEq(t, expected, string(out))
+
+		back, err := transfers.Reverse().Apply(out)
+		require.NoError(t, err)
+		require.JSONEq(t, data, string(back))
+	})
+}
+
+type shop struct {
+	Name  string          `json:"name"`
+	Users map[string]user `json:"users"`
+}
+
+func TestToGoTypeTransferMap(t *testing.T) {
+	transfers := pathtransfer.ToGoTypeTransfer(map[string]user{})
+	out, err := transfers.Apply([]byte(`{"1001":{"name":"张三","userId":"1001"},"1002":{"name":"李四","userId":"1002"}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"1001":{"name":"张三","userId":1001},"1002":{"name":"李四","userId":1002}}`, string(out))
+
+	transfers = pathtransfer.ToGoTypeTransfer(shop{})
+	out, err = transfers.Apply([]byte(`{"name":"店铺","users":{"a":{"name":"张三","userId":"1"}}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"店铺","users":{"a":{"name":"张三","userId":1}}}`, string(out))
+
+	transfers = pathtransfer.ToGoTypeTransfer(map[int]user{})
+	out, err = transfers.Apply([]byte(`{"1001":{"name":"张三","userId":"1001"}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"1001":{"name":"张三","userId":1001}}`, string(out))
+	var users map[int]user
+	require.NoError(t, json.Unmarshal(out, &users))
+	require.Equal(t, 1001, users[1001].UserId)
+}